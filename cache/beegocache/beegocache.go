@@ -0,0 +1,27 @@
+//Package beegocache 将 beego 的 cache 接入 gowechat
+//
+//单独成包，不使用beego的项目不会引入beego依赖
+package beegocache
+
+import (
+	"github.com/MrCHI/gowechat/cache"
+	bcache "github.com/astaxie/beego/cache"
+)
+
+//Wrap 将已有的 beego cache 转换为 cache.Cache
+//
+//beego 的 cache 没有 SET NX 等原子操作，不支持 cache.Locker：多个进程共用一个AppID时，
+//token过期时可能同时刷新，后刷新的会让先刷新的失效。多机部署时建议自己实现 cache.Locker，
+//创建Wechat时会输出一条Warn日志提示
+func Wrap(bc bcache.Cache) cache.Cache {
+	return bc
+}
+
+//New 使用 beego 的 adapter 创建缓存，例如 New("redis", `{"conn":"127.0.0.1:6379"}`)
+func New(adapterName, config string) (cache.Cache, error) {
+	bc, err := bcache.NewCache(adapterName, config)
+	if err != nil {
+		return nil, err
+	}
+	return Wrap(bc), nil
+}
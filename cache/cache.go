@@ -0,0 +1,53 @@
+//Package cache 保存access_token、jsapi_ticket、开放平台令牌等数据的存储接口
+//
+//内置了内存和文件两种实现，beego 的 cache 可以通过 cache/beegocache 接入，
+//其他存储（redis、memcache、数据库）只需要实现 Cache 接口。
+package cache
+
+import (
+	"encoding/json"
+	"time"
+)
+
+//Cache 存储接口，方法签名与 beego cache 保持一致
+//
+//SDK只保存字符串，实现可以只支持字符串类型的值
+type Cache interface {
+	//Get 获取值，不存在或已过期时返回nil
+	Get(key string) interface{}
+	//Put 设置值，timeout<=0 表示不过期
+	Put(key string, val interface{}, timeout time.Duration) error
+	//Delete 删除
+	Delete(key string) error
+	//IsExist 是否存在
+	IsExist(key string) bool
+}
+
+//GetString 获取字符串，不存在或类型不对时返回空字符串
+func GetString(c Cache, key string) string {
+	switch v := c.Get(key).(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	}
+	return ""
+}
+
+//GetJSON 读取PutJSON保存的值，不存在或解析失败时返回false
+func GetJSON(c Cache, key string, v interface{}) bool {
+	str := GetString(c, key)
+	if str == "" {
+		return false
+	}
+	return json.Unmarshal([]byte(str), v) == nil
+}
+
+//PutJSON 将v编码为json字符串保存
+func PutJSON(c Cache, key string, v interface{}, timeout time.Duration) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return c.Put(key, string(data), timeout)
+}
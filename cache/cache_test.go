@@ -0,0 +1,170 @@
+package cache
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCache(t *testing.T) {
+	stores := []struct {
+		name string
+		new  func(t *testing.T) Cache
+		//tick 过期时间的精度，文件缓存按秒保存
+		tick time.Duration
+	}{
+		{"memory", func(t *testing.T) Cache {
+			m := NewMemory(0)
+			t.Cleanup(func() { m.Close() })
+			return m
+		}, 10 * time.Millisecond},
+		{"file", func(t *testing.T) Cache {
+			f, err := NewFile(t.TempDir())
+			if err != nil {
+				t.Fatal(err)
+			}
+			return f
+		}, time.Second},
+	}
+	for _, store := range stores {
+		store := store
+		t.Run(store.name, func(t *testing.T) {
+			t.Parallel()
+			c := store.new(t)
+			tests := []struct {
+				name string
+				run  func(t *testing.T)
+			}{
+				{"PutGet", func(t *testing.T) {
+					if c.Get("missing") != nil || c.IsExist("missing") {
+						t.Error("missing key should be nil")
+					}
+					if err := c.Put("k", "v", 0); err != nil {
+						t.Fatal(err)
+					}
+					if GetString(c, "k") != "v" || !c.IsExist("k") {
+						t.Errorf("Get = %v", c.Get("k"))
+					}
+					c.Delete("k")
+					if c.IsExist("k") {
+						t.Error("key should be deleted")
+					}
+				}},
+				{"JSON", func(t *testing.T) {
+					if err := PutJSON(c, "json", map[string]int{"a": 1}, 0); err != nil {
+						t.Fatal(err)
+					}
+					var v map[string]int
+					if !GetJSON(c, "json", &v) || v["a"] != 1 {
+						t.Errorf("GetJSON = %v", v)
+					}
+				}},
+				{"Expiry", func(t *testing.T) {
+					c.Put("expiring", "v", store.tick)
+					c.Put("forever", "v", 0)
+					time.Sleep(2*store.tick + 50*time.Millisecond)
+					if c.IsExist("expiring") || !c.IsExist("forever") {
+						t.Errorf("expiring=%v forever=%v", c.Get("expiring"), c.Get("forever"))
+					}
+				}},
+				{"PutIfAbsent", func(t *testing.T) {
+					locker := c.(Locker)
+					if ok, err := locker.PutIfAbsent("lease", "a", 0); !ok || err != nil {
+						t.Fatalf("first PutIfAbsent = %v, %v", ok, err)
+					}
+					if ok, _ := locker.PutIfAbsent("lease", "b", 0); ok || GetString(c, "lease") != "a" {
+						t.Errorf("second PutIfAbsent should fail, value %v", c.Get("lease"))
+					}
+					if ok, _ := locker.CompareAndDelete("lease", "b"); ok {
+						t.Error("CompareAndDelete with another value should fail")
+					}
+					if ok, _ := locker.CompareAndDelete("lease", "a"); !ok || c.IsExist("lease") {
+						t.Error("CompareAndDelete with own value should delete")
+					}
+					if ok, _ := locker.PutIfAbsent("lease", "b", 0); !ok {
+						t.Error("PutIfAbsent after delete should succeed")
+					}
+				}},
+				{"Increase", func(t *testing.T) {
+					for want := int64(1); want <= 3; want++ {
+						if n, err := Increase(c, "counter", 0); n != want || err != nil {
+							t.Fatalf("Increase = %d, %v, want %d", n, err, want)
+						}
+					}
+					if GetInt(c, "counter") != 3 {
+						t.Errorf("GetInt = %d", GetInt(c, "counter"))
+					}
+				}},
+			}
+			for _, tt := range tests {
+				t.Run(tt.name, tt.run)
+			}
+		})
+	}
+}
+
+func TestFilePutIfAbsentConcurrent(t *testing.T) {
+	dir := t.TempDir()
+	//每个实例相当于一个进程，互相之间没有进程内的锁
+	files := make([]*File, 8)
+	for i := range files {
+		f, err := NewFile(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[i] = f
+	}
+	expired, err := json.Marshal(fileItem{Key: "lease", Val: "old", ExpireAt: time.Now().Add(-time.Minute).Unix()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for round := 0; round < 200; round++ {
+		files[0].Delete("lease")
+		if round%2 == 1 {
+			if err := ioutil.WriteFile(files[0].path("lease"), expired, 0600); err != nil {
+				t.Fatal(err)
+			}
+		}
+		var acquired int32
+		var wg sync.WaitGroup
+		start := make(chan struct{})
+		for i := range files {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				<-start
+				ok, err := files[i].PutIfAbsent("lease", fmt.Sprint(i), time.Minute)
+				if err != nil {
+					t.Error(err)
+				}
+				if ok {
+					atomic.AddInt32(&acquired, 1)
+				}
+			}(i)
+		}
+		close(start)
+		wg.Wait()
+		if acquired != 1 {
+			t.Fatalf("round %d: lease acquired %d times", round, acquired)
+		}
+	}
+}
+
+func TestMemoryGC(t *testing.T) {
+	m := NewMemory(5 * time.Millisecond)
+	defer m.Close()
+	m.Put("k", "v", time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+	m.mu.RLock()
+	n := len(m.items)
+	m.mu.RUnlock()
+	if n != 0 {
+		t.Errorf("expired item not collected, %d left", n)
+	}
+	m.Close()
+}
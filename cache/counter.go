@@ -0,0 +1,33 @@
+package cache
+
+import (
+	"strconv"
+	"time"
+)
+
+//Counter 支持原子自增的存储可以实现该接口，用于统计接口调用次数
+type Counter interface {
+	//Increase 将key的值加1并返回新值，key不存在时从0开始并设置timeout
+	Increase(key string, timeout time.Duration) (int64, error)
+}
+
+//Increase 计数加1，存储没有实现Counter时先读后写（多个进程同时写时会少计）
+func Increase(c Cache, key string, timeout time.Duration) (int64, error) {
+	if counter, ok := c.(Counter); ok {
+		return counter.Increase(key, timeout)
+	}
+	n := GetInt(c, key) + 1
+	return n, c.Put(key, strconv.FormatInt(n, 10), timeout)
+}
+
+//GetInt 读取计数，不存在时返回0
+func GetInt(c Cache, key string) int64 {
+	switch v := c.Get(key).(type) {
+	case int64:
+		return v
+	case int:
+		return int64(v)
+	}
+	n, _ := strconv.ParseInt(GetString(c, key), 10, 64)
+	return n
+}
@@ -0,0 +1,213 @@
+package cache
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+type fileItem struct {
+	Key      string `json:"key"`
+	Val      string `json:"val"`
+	ExpireAt int64  `json:"expire_at"`
+}
+
+func (item *fileItem) expired() bool {
+	return item.ExpireAt > 0 && time.Now().Unix() > item.ExpireAt
+}
+
+func fileValue(val interface{}) (string, bool) {
+	switch v := val.(type) {
+	case string:
+		return v, true
+	case []byte:
+		return string(v), true
+	}
+	return "", false
+}
+
+//File 文件缓存，每个key一个文件，适合单机多进程共享token
+//
+//只支持字符串和[]byte类型的值，读取时统一返回字符串
+type File struct {
+	dir string
+	mu  sync.RWMutex
+}
+
+//NewFile 实例化，dir不存在时自动创建
+func NewFile(dir string) (*File, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("create cache dir error : %v", err)
+	}
+	return &File{dir: dir}, nil
+}
+
+//Get 实现Cache
+func (f *File) Get(key string) interface{} {
+	f.mu.RLock()
+	item, ok := f.read(f.path(key))
+	f.mu.RUnlock()
+	if !ok || item.Key != key || item.expired() {
+		return nil
+	}
+	return item.Val
+}
+
+//Put 实现Cache
+func (f *File) Put(key string, val interface{}, timeout time.Duration) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	tmp, err := f.writeTemp(key, val, timeout)
+	if err != nil {
+		return err
+	}
+	//先写临时文件再改名，避免其他进程读到写了一半的内容
+	if err = os.Rename(tmp, f.path(key)); err != nil {
+		os.Remove(tmp)
+	}
+	return err
+}
+
+//PutIfAbsent 实现Locker，使用硬链接保证多个进程中只有一个设置成功
+func (f *File) PutIfAbsent(key string, val interface{}, timeout time.Duration) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	tmp, err := f.writeTemp(key, val, timeout)
+	if err != nil {
+		return false, err
+	}
+	defer os.Remove(tmp)
+
+	path := f.path(key)
+	data, err := ioutil.ReadFile(path)
+	switch {
+	case err == nil:
+		var item fileItem
+		if json.Unmarshal(data, &item) == nil && !item.expired() {
+			return false, nil
+		}
+		//过期（或损坏）的租约：读到同一个文件的进程先抢以内容命名的标记，只有抢到的删除它，
+		//其他进程不会误删之后的新租约
+		sum := md5.Sum(data)
+		claim := path + ".expired-" + hex.EncodeToString(sum[:])
+		if err = os.Link(tmp, claim); err != nil {
+			if os.IsExist(err) {
+				return false, nil
+			}
+			return false, err
+		}
+		f.removeClaims(path, claim)
+		if err = os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return false, err
+		}
+	case !os.IsNotExist(err):
+		return false, err
+	}
+	//只有硬链接因文件已存在失败才算没抢到
+	if err = os.Link(tmp, path); err != nil {
+		if os.IsExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+//CompareAndDelete 实现Locker
+//
+//读取和删除之间没有跨进程的锁，只用于释放自己持有的租约，租约的过期时间兜底
+func (f *File) CompareAndDelete(key string, val interface{}) (bool, error) {
+	str, ok := fileValue(val)
+	if !ok {
+		return false, fmt.Errorf("file cache only supports string value, got %T", val)
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	path := f.path(key)
+	if item, ok := f.read(path); !ok || item.expired() || item.Val != str {
+		return false, nil
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return false, err
+	}
+	f.removeClaims(path, "")
+	return true, nil
+}
+
+//Delete 实现Cache
+func (f *File) Delete(key string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	path := f.path(key)
+	f.removeClaims(path, "")
+	err := os.Remove(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+//IsExist 实现Cache
+func (f *File) IsExist(key string) bool {
+	return f.Get(key) != nil
+}
+
+//removeClaims 删除之前过期的租约留下的标记（保留keep），这时还读到旧租约的进程至少已经晚了一个租期
+func (f *File) removeClaims(path, keep string) {
+	claims, _ := filepath.Glob(path + ".expired-*")
+	for _, claim := range claims {
+		if claim != keep {
+			os.Remove(claim)
+		}
+	}
+}
+
+func (f *File) read(path string) (item fileItem, ok bool) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return
+	}
+	ok = json.Unmarshal(data, &item) == nil
+	return
+}
+
+//writeTemp 将值写入目录下的临时文件，返回临时文件路径
+func (f *File) writeTemp(key string, val interface{}, timeout time.Duration) (string, error) {
+	str, ok := fileValue(val)
+	if !ok {
+		return "", fmt.Errorf("file cache only supports string value, got %T", val)
+	}
+	item := fileItem{Key: key, Val: str}
+	if timeout > 0 {
+		item.ExpireAt = time.Now().Add(timeout).Unix()
+	}
+	data, err := json.Marshal(item)
+	if err != nil {
+		return "", err
+	}
+	tmp, err := ioutil.TempFile(f.dir, ".tmp-")
+	if err != nil {
+		return "", err
+	}
+	if _, err = tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	if err = tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	return tmp.Name(), nil
+}
+
+func (f *File) path(key string) string {
+	sum := md5.Sum([]byte(key))
+	return filepath.Join(f.dir, hex.EncodeToString(sum[:])+".json")
+}
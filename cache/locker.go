@@ -0,0 +1,21 @@
+package cache
+
+import "time"
+
+//Locker 支持原子操作的存储可以实现该接口，多个进程共用一个AppID时由此协调token的刷新
+//
+//没有实现该接口的存储只能保证进程内不重复刷新
+type Locker interface {
+	//PutIfAbsent key不存在（或已过期）时设置，返回是否设置成功
+	PutIfAbsent(key string, val interface{}, timeout time.Duration) (bool, error)
+	//CompareAndDelete 当前值等于val时删除，返回是否删除
+	CompareAndDelete(key string, val interface{}) (bool, error)
+}
+
+//Fencer 能够原子地“检查租约并写入”的存储可以实现该接口，例如redis的Lua脚本
+//
+//没有实现时先读取租约再写入，两步之间租约过期并被其他进程接手的极少数情况下，迟到的token仍可能覆盖新的token
+type Fencer interface {
+	//PutIfHeld leaseKey的当前值等于fence时设置key，返回是否设置
+	PutIfHeld(leaseKey string, fence interface{}, key string, val interface{}, timeout time.Duration) (bool, error)
+}
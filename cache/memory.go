@@ -0,0 +1,164 @@
+package cache
+
+import (
+	"strconv"
+	"sync"
+	"time"
+)
+
+//DefaultGCInterval 内存缓存清理过期数据的默认间隔
+const DefaultGCInterval = time.Minute
+
+type memoryItem struct {
+	val      interface{}
+	expireAt time.Time
+}
+
+func (item *memoryItem) expired(now time.Time) bool {
+	return !item.expireAt.IsZero() && now.After(item.expireAt)
+}
+
+//Memory 进程内缓存，多个进程部署时token会各自获取，请使用共享的存储
+type Memory struct {
+	mu    sync.RWMutex
+	items map[string]*memoryItem
+
+	stop      chan struct{}
+	closeOnce sync.Once
+}
+
+//NewMemory 实例化，gcInterval>0 时定期清理过期数据，不再使用时需要调用Close
+func NewMemory(gcInterval time.Duration) *Memory {
+	m := &Memory{items: make(map[string]*memoryItem), stop: make(chan struct{})}
+	if gcInterval > 0 {
+		go m.gc(gcInterval)
+	}
+	return m
+}
+
+//Close 停止定期清理，之后仍然可以读写，过期数据在读取时忽略
+func (m *Memory) Close() error {
+	m.closeOnce.Do(func() {
+		close(m.stop)
+	})
+	return nil
+}
+
+//Get 实现Cache
+func (m *Memory) Get(key string) interface{} {
+	m.mu.RLock()
+	item, ok := m.items[key]
+	m.mu.RUnlock()
+	if !ok || item.expired(time.Now()) {
+		return nil
+	}
+	return item.val
+}
+
+//Put 实现Cache
+func (m *Memory) Put(key string, val interface{}, timeout time.Duration) error {
+	item := &memoryItem{val: val}
+	if timeout > 0 {
+		item.expireAt = time.Now().Add(timeout)
+	}
+	m.mu.Lock()
+	m.items[key] = item
+	m.mu.Unlock()
+	return nil
+}
+
+//Delete 实现Cache
+func (m *Memory) Delete(key string) error {
+	m.mu.Lock()
+	delete(m.items, key)
+	m.mu.Unlock()
+	return nil
+}
+
+//IsExist 实现Cache
+func (m *Memory) IsExist(key string) bool {
+	return m.Get(key) != nil
+}
+
+//PutIfAbsent 实现Locker
+func (m *Memory) PutIfAbsent(key string, val interface{}, timeout time.Duration) (bool, error) {
+	item := &memoryItem{val: val}
+	if timeout > 0 {
+		item.expireAt = time.Now().Add(timeout)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if old, ok := m.items[key]; ok && !old.expired(time.Now()) {
+		return false, nil
+	}
+	m.items[key] = item
+	return true, nil
+}
+
+//CompareAndDelete 实现Locker
+func (m *Memory) CompareAndDelete(key string, val interface{}) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	item, ok := m.items[key]
+	if !ok || item.expired(time.Now()) || item.val != val {
+		return false, nil
+	}
+	delete(m.items, key)
+	return true, nil
+}
+
+//PutIfHeld 实现Fencer
+func (m *Memory) PutIfHeld(leaseKey string, fence interface{}, key string, val interface{}, timeout time.Duration) (bool, error) {
+	item := &memoryItem{val: val}
+	if timeout > 0 {
+		item.expireAt = time.Now().Add(timeout)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	lease, ok := m.items[leaseKey]
+	if !ok || lease.expired(time.Now()) || lease.val != fence {
+		return false, nil
+	}
+	m.items[key] = item
+	return true, nil
+}
+
+//Increase 实现Counter，值以字符串保存
+func (m *Memory) Increase(key string, timeout time.Duration) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var n int64
+	item, ok := m.items[key]
+	if ok && !item.expired(time.Now()) {
+		str, _ := item.val.(string)
+		n, _ = strconv.ParseInt(str, 10, 64)
+	} else {
+		item = &memoryItem{}
+		if timeout > 0 {
+			item.expireAt = time.Now().Add(timeout)
+		}
+		m.items[key] = item
+	}
+	n++
+	item.val = strconv.FormatInt(n, 10)
+	return n, nil
+}
+
+func (m *Memory) gc(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			m.mu.Lock()
+			for key, item := range m.items {
+				if item.expired(now) {
+					delete(m.items, key)
+				}
+			}
+			m.mu.Unlock()
+		case <-m.stop:
+			return
+		}
+	}
+}
@@ -1,23 +1,17 @@
 package main
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/MrCHI/gowechat"
+	"github.com/MrCHI/gowechat/mp/bridge/ginbridge"
 	"github.com/MrCHI/gowechat/mp/message"
 	"github.com/MrCHI/gowechat/wxcontext"
 	"github.com/gin-gonic/gin"
 )
 
 func main() {
-	router := gin.Default()
-
-	router.Any("/", hello)
-	router.Run(":8001")
-}
-
-func hello(c *gin.Context) {
-
 	//配置微信参数
 	config := wxcontext.Config{
 		AppID:          "your app id",
@@ -25,27 +19,20 @@ func hello(c *gin.Context) {
 		Token:          "your token",
 		EncodingAESKey: "your encoding aes key",
 	}
-	wc := gowechat.NewWechat(config)
-
-	mp, err := wc.MpMgr()
+	mp, err := gowechat.NewWechat(config).MpMgr()
 	if err != nil {
+		fmt.Println(err)
 		return
 	}
 
-	// 传入request和responseWriter
-	msgHandler := mp.GetMsgHandler(c.Request, c.Writer)
 	//设置接收消息的处理方法
-	msgHandler.SetHandleMessageFunc(func(msg message.MixMessage) *message.Reply {
-
+	msgRouter := message.NewRouter()
+	msgRouter.Text(func(ctx context.Context, msg *message.MixMessage) *message.Reply {
 		//回复消息：演示回复用户发送的消息
-		text := message.NewText(msg.Content)
-		return &message.Reply{message.MsgTypeText, text}
+		return &message.Reply{MsgType: message.MsgTypeText, MsgData: message.NewText(msg.Content)}
 	})
 
-	//处理消息接收以及回复
-	err = msgHandler.Handle()
-	if err != nil {
-		fmt.Println(err)
-		return
-	}
+	router := gin.Default()
+	router.Any("/", ginbridge.Handler(mp.NewMsgServer(msgRouter.Serve)))
+	router.Run(":8001")
 }
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 
@@ -9,8 +10,7 @@ import (
 	"github.com/MrCHI/gowechat/wxcontext"
 )
 
-func hello(rw http.ResponseWriter, req *http.Request) {
-
+func main() {
 	//配置微信参数
 	config := wxcontext.Config{
 		AppID:          "your app id",
@@ -18,34 +18,22 @@ func hello(rw http.ResponseWriter, req *http.Request) {
 		Token:          "your token",
 		EncodingAESKey: "your encoding aes key",
 	}
-	wc := gowechat.NewWechat(config)
-
-	mp, err := wc.MpMgr()
+	mp, err := gowechat.NewWechat(config).MpMgr()
 	if err != nil {
+		fmt.Println(err)
 		return
 	}
 
-	// 传入request和responseWriter
-	msgHandler := mp.GetMsgHandler(req, rw)
 	//设置接收消息的处理方法
-	msgHandler.SetHandleMessageFunc(func(msg message.MixMessage) *message.Reply {
-
+	router := message.NewRouter()
+	router.Text(func(ctx context.Context, msg *message.MixMessage) *message.Reply {
 		//回复消息：演示回复用户发送的消息
-		text := message.NewText(msg.Content)
-		return &message.Reply{message.MsgTypeText, text}
+		return &message.Reply{MsgType: message.MsgTypeText, MsgData: message.NewText(msg.Content)}
 	})
 
-	//处理消息接收以及回复
-	err = msgHandler.Handle()
-	if err != nil {
-		fmt.Println(err)
-		return
-	}
-}
-
-func main() {
-	http.HandleFunc("/", hello)
-	err := http.ListenAndServe(":8001", nil)
+	//启动时创建一次，可以并发处理请求
+	http.Handle("/", mp.NewMsgServer(router.Serve))
+	err = http.ListenAndServe(":8001", nil)
 	if err != nil {
 		fmt.Printf("start server error , err=%v", err)
 	}
@@ -0,0 +1,67 @@
+//Package httpreplay 录制与回放微信接口的HTTP请求，用于编写不依赖网络的测试
+//
+//录制时使用真实的账号请求一次，把请求和响应（已去掉secret、token、签名等）保存到文件：
+//
+//	rec := httpreplay.NewRecorder("testdata/menu.json")
+//	config.HTTPMiddlewares = []wxcontext.HTTPMiddleware{rec.Middleware}
+//	... 调用接口 ...
+//	rec.Save()
+//
+//测试时回放，不会发出任何请求：
+//
+//	rp, err := httpreplay.NewReplayer("testdata/menu.json")
+//	config.HTTPMiddlewares = []wxcontext.HTTPMiddleware{rp.Middleware}
+package httpreplay
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+)
+
+//Fixture 录制的文件内容
+type Fixture struct {
+	Interactions []*Interaction `json:"interactions"`
+}
+
+//Interaction 一次请求和响应
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+//Request 录制的请求，URL只保留路径和参数
+type Request struct {
+	Method string `json:"method"`
+	URL    string `json:"url"`
+	Body   string `json:"body,omitempty"`
+}
+
+//Response 录制的响应
+type Response struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body"`
+}
+
+//LoadFixture 读取录制的文件
+func LoadFixture(path string) (*Fixture, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	fixture := new(Fixture)
+	if err = json.Unmarshal(data, fixture); err != nil {
+		return nil, err
+	}
+	return fixture, nil
+}
+
+//Save 保存到文件
+func (f *Fixture) Save(path string) error {
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append(data, '\n'), 0644)
+}
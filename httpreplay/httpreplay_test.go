@@ -0,0 +1,84 @@
+package httpreplay
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/cgi-bin/token":
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, `{"access_token":"real-token","expires_in":7200}`)
+		case "/pay/orderquery":
+			w.Header().Set("Content-Type", "text/xml")
+			fmt.Fprint(w, `<xml><return_code><![CDATA[SUCCESS]]></return_code><sign><![CDATA[REALSIGN]]></sign></xml>`)
+		}
+	}))
+	defer srv.Close()
+
+	path := filepath.Join(t.TempDir(), "fixture.json")
+	rec := NewRecorder(path)
+	client := &http.Client{Transport: rec.Middleware(http.DefaultTransport)}
+	resp, err := client.Get(srv.URL + "/cgi-bin/token?grant_type=client_credential&appid=wx1&secret=real-secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !strings.Contains(string(body), "real-token") {
+		t.Fatalf("recorder changed the live response: %s", body)
+	}
+	resp, err = client.Post(srv.URL+"/pay/orderquery", "text/xml", strings.NewReader(`<xml><sign>REQSIGN</sign></xml>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if err = rec.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"real-token", "real-secret", "REALSIGN", "REQSIGN"} {
+		if strings.Contains(string(data), secret) {
+			t.Errorf("fixture contains %q:\n%s", secret, data)
+		}
+	}
+
+	rp, err := NewReplayer(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rp.MchAPIKey = "key"
+	client = &http.Client{Transport: rp.Middleware(nil)}
+	resp, err = client.Get("https://api.weixin.qq.com/cgi-bin/token?grant_type=client_credential&appid=wx1&secret=other")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ = ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !strings.Contains(string(body), Scrubbed) {
+		t.Errorf("unexpected replayed body %s", body)
+	}
+	resp, err = client.Post("https://api.mch.weixin.qq.com/pay/orderquery", "text/xml", strings.NewReader(`<xml/>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ = ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if strings.Contains(string(body), Scrubbed) {
+		t.Errorf("xml response was not re-signed: %s", body)
+	}
+	if _, err = client.Get("https://api.weixin.qq.com/cgi-bin/token"); err == nil {
+		t.Error("expected error when recorded responses are used up")
+	}
+}
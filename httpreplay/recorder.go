@@ -0,0 +1,89 @@
+package httpreplay
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"sync"
+)
+
+//Recorder 录制经过的请求和响应
+type Recorder struct {
+	path     string
+	scrubber *scrubber
+
+	mu      sync.Mutex
+	fixture Fixture
+}
+
+//NewRecorder 实例化，extraKeys为SecretKeys之外需要去掉的字段
+func NewRecorder(path string, extraKeys ...string) *Recorder {
+	return &Recorder{path: path, scrubber: newScrubber(extraKeys)}
+}
+
+//Middleware 实现 wxcontext.HTTPMiddleware
+func (r *Recorder) Middleware(next http.RoundTripper) http.RoundTripper {
+	return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		return r.roundTrip(next, req)
+	})
+}
+
+func (r *Recorder) roundTrip(next http.RoundTripper, req *http.Request) (*http.Response, error) {
+	var reqBody []byte
+	if req.Body != nil {
+		var err error
+		if reqBody, err = ioutil.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+		req.Body = ioutil.NopCloser(bytes.NewReader(reqBody))
+	}
+
+	resp, err := next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(respBody))
+
+	interaction := &Interaction{
+		Request: Request{
+			Method: req.Method,
+			URL:    r.scrubber.url(req.URL),
+		},
+		Response: Response{
+			StatusCode: resp.StatusCode,
+			Header:     http.Header{"Content-Type": resp.Header["Content-Type"]},
+			Body:       string(r.scrubber.body(respBody)),
+		},
+	}
+	//上传文件等二进制内容不保存
+	if isText(req.Header.Get("Content-Type")) {
+		interaction.Request.Body = string(r.scrubber.body(reqBody))
+	}
+	if !isText(resp.Header.Get("Content-Type")) && !isText(http.DetectContentType(respBody)) {
+		interaction.Response.Body = ""
+	}
+
+	r.mu.Lock()
+	r.fixture.Interactions = append(r.fixture.Interactions, interaction)
+	r.mu.Unlock()
+	return resp, nil
+}
+
+//Save 保存到文件
+func (r *Recorder) Save() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.fixture.Save(r.path)
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
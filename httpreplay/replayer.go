@@ -0,0 +1,127 @@
+package httpreplay
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/MrCHI/gowechat/mch/base"
+)
+
+//Replayer 按录制的内容返回响应，不发出真实请求
+//
+//同一个方法和路径的请求按录制的顺序依次返回，参数和请求体不参与匹配
+type Replayer struct {
+	//MchAPIKey 不为空时，用它重新计算xml响应中的sign（录制时sign已被去掉），使商户平台接口的验签通过
+	MchAPIKey string
+
+	scrubber *scrubber
+
+	mu     sync.Mutex
+	queues map[string][]*Interaction
+}
+
+//NewReplayer 读取录制的文件
+func NewReplayer(path string) (*Replayer, error) {
+	fixture, err := LoadFixture(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewReplayerFromFixture(fixture), nil
+}
+
+//NewReplayerFromFixture 使用已有的录制内容
+func NewReplayerFromFixture(fixture *Fixture) *Replayer {
+	r := &Replayer{scrubber: newScrubber(nil), queues: make(map[string][]*Interaction)}
+	for _, interaction := range fixture.Interactions {
+		key := matchKey(interaction.Request.Method, interaction.Request.URL)
+		r.queues[key] = append(r.queues[key], interaction)
+	}
+	return r
+}
+
+//Middleware 实现 wxcontext.HTTPMiddleware，不会调用next
+func (r *Replayer) Middleware(next http.RoundTripper) http.RoundTripper {
+	return r
+}
+
+//RoundTrip 实现 http.RoundTripper
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+	key := matchKey(req.Method, r.scrubber.url(req.URL))
+	r.mu.Lock()
+	queue := r.queues[key]
+	if len(queue) == 0 {
+		r.mu.Unlock()
+		return nil, fmt.Errorf("httpreplay: no recorded response for %s", key)
+	}
+	interaction := queue[0]
+	r.queues[key] = queue[1:]
+	r.mu.Unlock()
+
+	body := []byte(interaction.Response.Body)
+	if r.MchAPIKey != "" && strings.HasPrefix(strings.TrimSpace(interaction.Response.Body), "<") {
+		body = r.resign(body)
+	}
+	header := make(http.Header)
+	for k, v := range interaction.Response.Header {
+		header[k] = v
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", interaction.Response.StatusCode, http.StatusText(interaction.Response.StatusCode)),
+		StatusCode:    interaction.Response.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          ioutil.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
+
+//Remaining 还没有被使用的录制数量，测试结束时可以检查是否所有请求都发生了
+func (r *Replayer) Remaining() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	n := 0
+	for _, queue := range r.queues {
+		n += len(queue)
+	}
+	return n
+}
+
+//resign 重新计算商户平台响应的签名
+func (r *Replayer) resign(body []byte) []byte {
+	m, err := base.ParseXMLToMap(bytes.NewReader(body))
+	if err != nil {
+		return body
+	}
+	if _, ok := m["sign"]; !ok {
+		return body
+	}
+	m["sign"] = base.Sign(m, r.MchAPIKey, nil)
+	var buf bytes.Buffer
+	if err = base.FormatMapToXML(&buf, m); err != nil {
+		return body
+	}
+	return buf.Bytes()
+}
+
+//matchKey 方法和路径，忽略参数
+func matchKey(method, url string) string {
+	if i := strings.IndexByte(url, '?'); i >= 0 {
+		url = url[:i]
+	}
+	return method + " " + url
+}
+
+func isText(contentType string) bool {
+	return strings.Contains(contentType, "json") || strings.Contains(contentType, "xml") ||
+		strings.HasPrefix(contentType, "text/")
+}
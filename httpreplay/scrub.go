@@ -0,0 +1,108 @@
+package httpreplay
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+//Scrubbed 替换敏感信息后的值
+const Scrubbed = "SCRUBBED"
+
+//SecretKeys 录制时需要去掉的参数和字段（url参数、json字段、xml元素）
+var SecretKeys = []string{
+	"access_token",
+	"refresh_token",
+	"secret",
+	"appsecret",
+	"code",
+	"ticket",
+	"sign",
+	"paySign",
+	"component_appsecret",
+	"component_access_token",
+	"component_verify_ticket",
+	"authorizer_access_token",
+	"authorizer_refresh_token",
+	"pre_auth_code",
+	"authorization_code",
+}
+
+type scrubber struct {
+	keys  map[string]bool
+	xmlRe *regexp.Regexp
+}
+
+func newScrubber(extraKeys []string) *scrubber {
+	s := &scrubber{keys: make(map[string]bool)}
+	names := make([]string, 0, len(SecretKeys)+len(extraKeys))
+	for _, key := range append(append([]string{}, SecretKeys...), extraKeys...) {
+		if !s.keys[key] {
+			s.keys[key] = true
+			names = append(names, regexp.QuoteMeta(key))
+		}
+	}
+	s.xmlRe = regexp.MustCompile(`<(` + strings.Join(names, "|") + `)>(<!\[CDATA\[)?[^<]*(\]\]>)?</(` + strings.Join(names, "|") + `)>`)
+	return s
+}
+
+//url 去掉host，替换敏感的参数
+func (s *scrubber) url(u *url.URL) string {
+	query := u.Query()
+	for key := range query {
+		if s.keys[key] {
+			query.Set(key, Scrubbed)
+		}
+	}
+	result := u.EscapedPath()
+	if len(query) > 0 {
+		result += "?" + query.Encode()
+	}
+	return result
+}
+
+//body 替换json或xml中的敏感字段，其他格式原样返回
+func (s *scrubber) body(body []byte) []byte {
+	trimmed := bytes.TrimSpace(body)
+	if len(trimmed) == 0 {
+		return body
+	}
+	switch trimmed[0] {
+	case '{', '[':
+		var v interface{}
+		if err := json.Unmarshal(trimmed, &v); err != nil {
+			return body
+		}
+		data, err := json.Marshal(s.json(v))
+		if err != nil {
+			return body
+		}
+		return data
+	case '<':
+		return s.xmlRe.ReplaceAllFunc(body, func(m []byte) []byte {
+			sub := s.xmlRe.FindSubmatch(m)
+			return []byte("<" + string(sub[1]) + ">" + Scrubbed + "</" + string(sub[4]) + ">")
+		})
+	}
+	return body
+}
+
+func (s *scrubber) json(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for key, item := range val {
+			if _, isString := item.(string); isString && s.keys[key] {
+				val[key] = Scrubbed
+				continue
+			}
+			val[key] = s.json(item)
+		}
+	case []interface{}:
+		for i, item := range val {
+			val[i] = s.json(item)
+		}
+	}
+	return v
+}
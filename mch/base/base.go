@@ -2,11 +2,13 @@ package base
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 
+	"github.com/MrCHI/gowechat/util"
 	"github.com/MrCHI/gowechat/wxcontext"
 )
 
@@ -17,6 +19,11 @@ type MchBase struct {
 
 //PostXML postXML
 func (c *MchBase) PostXML(url string, req map[string]string, needSSL bool) (resp map[string]string, err error) {
+	return c.PostXMLContext(context.Background(), url, req, needSSL)
+}
+
+//PostXMLContext 同 PostXML，支持 context
+func (c *MchBase) PostXMLContext(ctx context.Context, url string, req map[string]string, needSSL bool) (resp map[string]string, err error) {
 	bodyBuf := textBufferPool.Get().(*bytes.Buffer)
 	bodyBuf.Reset()
 	defer textBufferPool.Put(bodyBuf)
@@ -26,12 +33,28 @@ func (c *MchBase) PostXML(url string, req map[string]string, needSSL bool) (resp
 	}
 
 	//需要ssl，就需要ssl client
-	client := c.HTTPClient
+	client := c.GetHTTPClient()
 	if needSSL {
-		client = c.SHTTPClient
+		if client, err = c.GetSHTTPClient(); err != nil {
+			return
+		}
 	}
 
-	httpResp, err := client.Post(url, "text/xml; charset=utf-8", bodyBuf)
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.ResolveURL(url), bodyBuf)
+	if err != nil {
+		return
+	}
+	//xml响应中的业务结果需要解析后才知道，所以由这里统计，不经过interceptTransport
+	ctx, info := c.StartCall(ctx, wxcontext.CallOutbound, httpReq.URL.Path)
+	defer func() {
+		if info != nil && resp != nil {
+			info.MchCode = mchCode(resp)
+		}
+		c.EndCall(ctx, info, err)
+	}()
+	httpReq = httpReq.WithContext(ctx)
+	httpReq.Header.Set("Content-Type", "text/xml; charset=utf-8")
+	httpResp, err := client.Do(httpReq)
 	if err != nil {
 		return resp, err
 	}
@@ -88,9 +111,23 @@ func (c *MchBase) PostXML(url string, req map[string]string, needSSL bool) (resp
 		}
 		signature2 := Sign(resp, c.MchAPIKey, nil)
 		if signature1 != signature2 {
-			err = fmt.Errorf("check signature failed, \r\ninput: %q, \r\nlocal: %q", signature1, signature2)
+			err = fmt.Errorf("check signature failed, \r\ninput: %q, \r\nlocal: %q", util.RedactValue(signature1), util.RedactValue(signature2))
 			return
 		}
 	}
 	return
 }
+
+//mchCode 返回响应中的错误码，成功时为空
+func mchCode(resp map[string]string) string {
+	if code := resp["return_code"]; code != ReturnCodeSuccess {
+		return code
+	}
+	if resp["result_code"] == ResultCodeFail {
+		if code := resp["err_code"]; code != "" {
+			return code
+		}
+		return ResultCodeFail
+	}
+	return ""
+}
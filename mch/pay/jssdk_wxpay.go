@@ -2,6 +2,7 @@ package pay
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"time"
@@ -58,13 +59,18 @@ func (c *WxPayInfo) ToMap() (m map[string]string) {
 /*GetJsAPIConfig 前端JsAPI支付时,需要提交的信息
  */
 func (c *Pay) GetJsAPIConfig(order OrderInput) (config *WxPayInfo, err error) {
+	return c.GetJsAPIConfigContext(context.Background(), order)
+}
+
+//GetJsAPIConfigContext 同 GetJsAPIConfig，支持 context
+func (c *Pay) GetJsAPIConfigContext(ctx context.Context, order OrderInput) (config *WxPayInfo, err error) {
 	order.setTradeType("JSAPI")
 	err = c.checkOrder(order)
 	if err != nil {
 		return
 	}
 	var prepayID string
-	prepayID, err = c.getPrepayID(order)
+	prepayID, err = c.getPrepayID(ctx, order)
 	if err != nil {
 		return
 	}
@@ -97,10 +103,15 @@ func (c *Pay) GetJsAPIConfig(order OrderInput) (config *WxPayInfo, err error) {
 
 //GetNativePayQrcodePicURL native支付时二维码图片的url
 func (c *Pay) GetNativePayQrcodePicURL(order OrderInput) (qrcodeURL string, err error) {
+	return c.GetNativePayQrcodePicURLContext(context.Background(), order)
+}
+
+//GetNativePayQrcodePicURLContext 同 GetNativePayQrcodePicURL，支持 context
+func (c *Pay) GetNativePayQrcodePicURLContext(ctx context.Context, order OrderInput) (qrcodeURL string, err error) {
 	order.setTradeType("NATIVE")
 	input := c.createUnifiedOrderMap(order)
 	var result map[string]string
-	if result, err = c.UnifiedOrder(input); err == nil { //有prepay_id
+	if result, err = c.UnifiedOrderContext(ctx, input); err == nil { //有prepay_id
 		qrcodeURL = result["code_url"]
 		if len(qrcodeURL) == 0 {
 			err = fmt.Errorf("native pay Qrcode url is empty")
@@ -110,10 +121,10 @@ func (c *Pay) GetNativePayQrcodePicURL(order OrderInput) (qrcodeURL string, err
 }
 
 // 调用 UnifiedOrder 获得 prepayID
-func (c *Pay) getPrepayID(order OrderInput) (prepayID string, err error) {
+func (c *Pay) getPrepayID(ctx context.Context, order OrderInput) (prepayID string, err error) {
 	input := c.createUnifiedOrderMap(order)
 	var result map[string]string
-	if result, err = c.UnifiedOrder(input); err == nil { //有prepay_id
+	if result, err = c.UnifiedOrderContext(ctx, input); err == nil { //有prepay_id
 		prepayID := result["prepay_id"]
 		if prepayID != "" {
 			return prepayID, nil
@@ -221,7 +232,7 @@ func (c *Pay) CheckPayNotifyData(data []byte) (isSuccess bool, err error) {
 		signature2 := base.Sign(msg, c.MchAPIKey, nil)
 
 		if signature1 != signature2 {
-			err = fmt.Errorf("Sign is not same. sige_got is %s, sign_mine is %s", signature1, signature2)
+			err = fmt.Errorf("Sign is not same. sige_got is %s, sign_mine is %s", util.RedactValue(signature1), util.RedactValue(signature2))
 			return
 		}
 
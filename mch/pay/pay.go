@@ -1,6 +1,7 @@
 package pay
 
 import (
+	"context"
 	"github.com/MrCHI/gowechat/mch/base"
 	"github.com/MrCHI/gowechat/wxcontext"
 )
@@ -19,26 +20,51 @@ func NewPay(context *wxcontext.Context) *Pay {
 
 //UnifiedOrder 统一下单.
 func (c *Pay) UnifiedOrder(req map[string]string) (resp map[string]string, err error) {
-	return c.PostXML("https://api.mch.weixin.qq.com/pay/unifiedorder", req, false)
+	return c.UnifiedOrderContext(context.Background(), req)
+}
+
+//UnifiedOrderContext 同 UnifiedOrder，支持 context
+func (c *Pay) UnifiedOrderContext(ctx context.Context, req map[string]string) (resp map[string]string, err error) {
+	return c.PostXMLContext(ctx, "https://api.mch.weixin.qq.com/pay/unifiedorder", req, false)
 }
 
 //OrderQuery 查询订单.
 func (c *Pay) OrderQuery(req map[string]string) (resp map[string]string, err error) {
-	return c.PostXML("https://api.mch.weixin.qq.com/pay/orderquery", req, false)
+	return c.OrderQueryContext(context.Background(), req)
+}
+
+//OrderQueryContext 同 OrderQuery，支持 context
+func (c *Pay) OrderQueryContext(ctx context.Context, req map[string]string) (resp map[string]string, err error) {
+	return c.PostXMLContext(ctx, "https://api.mch.weixin.qq.com/pay/orderquery", req, false)
 }
 
 //CloseOrder 关闭订单.
 func (c *Pay) CloseOrder(req map[string]string) (resp map[string]string, err error) {
-	return c.PostXML("https://api.mch.weixin.qq.com/pay/closeorder", req, false)
+	return c.CloseOrderContext(context.Background(), req)
+}
+
+//CloseOrderContext 同 CloseOrder，支持 context
+func (c *Pay) CloseOrderContext(ctx context.Context, req map[string]string) (resp map[string]string, err error) {
+	return c.PostXMLContext(ctx, "https://api.mch.weixin.qq.com/pay/closeorder", req, false)
 }
 
 //Refund 申请退款.
 //  NOTE: 请求需要双向证书.
 func (c *Pay) Refund(req map[string]string) (resp map[string]string, err error) {
-	return c.PostXML("https://api.mch.weixin.qq.com/secapi/pay/refund", req, true)
+	return c.RefundContext(context.Background(), req)
+}
+
+//RefundContext 同 Refund，支持 context
+func (c *Pay) RefundContext(ctx context.Context, req map[string]string) (resp map[string]string, err error) {
+	return c.PostXMLContext(ctx, "https://api.mch.weixin.qq.com/secapi/pay/refund", req, true)
 }
 
 //RefundQuery 查询退款.
 func (c *Pay) RefundQuery(req map[string]string) (resp map[string]string, err error) {
-	return c.PostXML("https://api.mch.weixin.qq.com/pay/refundquery", req, false)
+	return c.RefundQueryContext(context.Background(), req)
+}
+
+//RefundQueryContext 同 RefundQuery，支持 context
+func (c *Pay) RefundQueryContext(ctx context.Context, req map[string]string) (resp map[string]string, err error) {
+	return c.PostXMLContext(ctx, "https://api.mch.weixin.qq.com/pay/refundquery", req, false)
 }
@@ -1,6 +1,8 @@
 package paytool
 
 import (
+	"context"
+
 	"github.com/MrCHI/gowechat/mch/base"
 	"github.com/MrCHI/gowechat/wxcontext"
 )
@@ -19,5 +21,10 @@ func NewPayTool(context *wxcontext.Context) *PayTool {
 
 //SendRedPackRaw 发现金红包
 func (c *PayTool) SendRedPackRaw(req map[string]string) (resp map[string]string, err error) {
-	return c.PostXML("https://api.mch.weixin.qq.com/mmpaymkttransfers/sendredpack", req, true)
+	return c.SendRedPackRawContext(context.Background(), req)
+}
+
+//SendRedPackRawContext 同 SendRedPackRaw，支持 context
+func (c *PayTool) SendRedPackRawContext(ctx context.Context, req map[string]string) (resp map[string]string, err error) {
+	return c.PostXMLContext(ctx, "https://api.mch.weixin.qq.com/mmpaymkttransfers/sendredpack", req, true)
 }
@@ -1,11 +1,11 @@
 package paytool
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"time"
 
-	"github.com/astaxie/beego"
 	"github.com/MrCHI/gowechat/mch/base"
 	"github.com/MrCHI/gowechat/util"
 )
@@ -74,12 +74,17 @@ func (m *RedPackInput) Check() (isGood bool, err error) {
 
 //SendRedPack 发红包
 func (c *PayTool) SendRedPack(input RedPackInput) (isSuccess bool, err error) {
+	return c.SendRedPackContext(context.Background(), input)
+}
+
+//SendRedPackContext 同 SendRedPack，支持 context
+func (c *PayTool) SendRedPackContext(ctx context.Context, input RedPackInput) (isSuccess bool, err error) {
 	if isGood, err := input.Check(); !isGood {
 		return false, err
 	}
 
 	now := time.Now()
-	dayStr := beego.Date(now, "Ymd")
+	dayStr := now.Format("20060102")
 
 	billno := c.MchID + dayStr + util.RandomStr(10)
 
@@ -98,7 +103,7 @@ func (c *PayTool) SendRedPack(input RedPackInput) (isSuccess bool, err error) {
 	signMap["remark"] = input.Remark
 	signMap["sign"] = base.Sign(signMap, c.MchAPIKey, nil)
 
-	respMap, err := c.SendRedPackRaw(signMap)
+	respMap, err := c.SendRedPackRawContext(ctx, signMap)
 	if err != nil {
 		return false, err
 	}
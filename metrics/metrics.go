@@ -0,0 +1,208 @@
+//Package metrics 统计调用微信接口、处理推送消息的次数和耗时，输出 Prometheus 文本格式
+//
+//	collector := metrics.NewCollector()
+//	config.Interceptors = []wxcontext.Interceptor{collector}
+//	http.Handle("/metrics", collector)
+package metrics
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/MrCHI/gowechat/wxcontext"
+)
+
+//DefaultBuckets 耗时直方图默认的区间（秒）
+var DefaultBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+const (
+	resultOK       = "ok"
+	resultAPIError = "api_error"
+	resultError    = "error"
+)
+
+type callKey struct {
+	kind, endpoint, appID, errCode, result string
+}
+
+func (k callKey) String() string {
+	return strings.Join([]string{k.kind, k.endpoint, k.appID, k.errCode, k.result}, "\x00")
+}
+
+type durationKey struct {
+	kind, endpoint, appID string
+}
+
+func (k durationKey) String() string {
+	return strings.Join([]string{k.kind, k.endpoint, k.appID}, "\x00")
+}
+
+type histogram struct {
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+//Collector 内存中的统计，实现 wxcontext.Interceptor 和 http.Handler
+type Collector struct {
+	buckets []float64
+
+	mu        sync.Mutex
+	calls     map[callKey]uint64
+	retries   map[durationKey]uint64
+	durations map[durationKey]*histogram
+}
+
+//NewCollector 实例化，buckets为空时使用DefaultBuckets
+func NewCollector(buckets ...float64) *Collector {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+	return &Collector{
+		buckets:   buckets,
+		calls:     make(map[callKey]uint64),
+		retries:   make(map[durationKey]uint64),
+		durations: make(map[durationKey]*histogram),
+	}
+}
+
+//Before 实现 wxcontext.Interceptor
+func (c *Collector) Before(ctx context.Context, info *wxcontext.CallInfo) context.Context {
+	return ctx
+}
+
+//After 实现 wxcontext.Interceptor
+func (c *Collector) After(ctx context.Context, info *wxcontext.CallInfo) {
+	result := resultOK
+	errCode := strconv.FormatInt(info.ErrCode, 10)
+	switch {
+	case info.MchCode != "":
+		result = resultAPIError
+		errCode = info.MchCode
+	case info.ErrCode != 0:
+		result = resultAPIError
+	case info.Err != nil:
+		result = resultError
+	}
+	dk := durationKey{info.Kind, info.Endpoint, info.AppID}
+	ck := callKey{info.Kind, info.Endpoint, info.AppID, errCode, result}
+	seconds := info.Latency.Seconds()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.calls[ck]++
+	if info.Retries > 0 {
+		c.retries[dk]++
+	}
+	h, ok := c.durations[dk]
+	if !ok {
+		h = &histogram{counts: make([]uint64, len(c.buckets))}
+		c.durations[dk] = h
+	}
+	for i, le := range c.buckets {
+		if seconds <= le {
+			h.counts[i]++
+		}
+	}
+	h.sum += seconds
+	h.count++
+}
+
+//ServeHTTP 输出统计结果
+func (c *Collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	c.WriteTo(w)
+}
+
+//WriteTo 以 Prometheus 文本格式输出
+func (c *Collector) WriteTo(w io.Writer) (int64, error) {
+	cw := &countWriter{w: bufio.NewWriter(w)}
+
+	c.mu.Lock()
+	callKeys := make([]callKey, 0, len(c.calls))
+	for k := range c.calls {
+		callKeys = append(callKeys, k)
+	}
+	sort.Slice(callKeys, func(i, j int) bool {
+		return callKeys[i].String() < callKeys[j].String()
+	})
+	fmt.Fprintln(cw, "# HELP wechat_calls_total Number of WeChat API calls and inbound messages.")
+	fmt.Fprintln(cw, "# TYPE wechat_calls_total counter")
+	for _, k := range callKeys {
+		fmt.Fprintf(cw, "wechat_calls_total{%s,errcode=%q,result=%q} %d\n",
+			labels(k.kind, k.endpoint, k.appID), k.errCode, k.result, c.calls[k])
+	}
+
+	retryKeys := make([]durationKey, 0, len(c.retries))
+	for k := range c.retries {
+		retryKeys = append(retryKeys, k)
+	}
+	sortDurationKeys(retryKeys)
+	fmt.Fprintln(cw, "# HELP wechat_call_retries_total Number of retried WeChat API calls.")
+	fmt.Fprintln(cw, "# TYPE wechat_call_retries_total counter")
+	for _, k := range retryKeys {
+		fmt.Fprintf(cw, "wechat_call_retries_total{%s} %d\n", labels(k.kind, k.endpoint, k.appID), c.retries[k])
+	}
+
+	durationKeys := make([]durationKey, 0, len(c.durations))
+	for k := range c.durations {
+		durationKeys = append(durationKeys, k)
+	}
+	sortDurationKeys(durationKeys)
+	fmt.Fprintln(cw, "# HELP wechat_call_duration_seconds Latency of WeChat API calls and inbound messages.")
+	fmt.Fprintln(cw, "# TYPE wechat_call_duration_seconds histogram")
+	for _, k := range durationKeys {
+		h := c.durations[k]
+		l := labels(k.kind, k.endpoint, k.appID)
+		for i, le := range c.buckets {
+			fmt.Fprintf(cw, "wechat_call_duration_seconds_bucket{%s,le=%q} %d\n", l, strconv.FormatFloat(le, 'g', -1, 64), h.counts[i])
+		}
+		fmt.Fprintf(cw, "wechat_call_duration_seconds_bucket{%s,le=\"+Inf\"} %d\n", l, h.count)
+		fmt.Fprintf(cw, "wechat_call_duration_seconds_sum{%s} %g\n", l, h.sum)
+		fmt.Fprintf(cw, "wechat_call_duration_seconds_count{%s} %d\n", l, h.count)
+	}
+	c.mu.Unlock()
+
+	if cw.err == nil {
+		cw.err = cw.w.Flush()
+	}
+	return cw.n, cw.err
+}
+
+func sortDurationKeys(keys []durationKey) {
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].String() < keys[j].String()
+	})
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func labels(kind, endpoint, appID string) string {
+	return fmt.Sprintf(`kind="%s",endpoint="%s",appid="%s"`,
+		labelEscaper.Replace(kind), labelEscaper.Replace(endpoint), labelEscaper.Replace(appID))
+}
+
+type countWriter struct {
+	w   *bufio.Writer
+	n   int64
+	err error
+}
+
+func (cw *countWriter) Write(p []byte) (int, error) {
+	if cw.err != nil {
+		return 0, cw.err
+	}
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	cw.err = err
+	return n, err
+}
@@ -0,0 +1,77 @@
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/MrCHI/gowechat/mch/base"
+	"github.com/MrCHI/gowechat/wxcontext"
+)
+
+func TestCollector(t *testing.T) {
+	//每个接口第一次失败，第二次成功
+	served := make(map[string]int)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		served[r.URL.Path]++
+		fail := served[r.URL.Path] == 1
+		if r.URL.Path == "/cgi-bin/token" {
+			w.Header().Set("Content-Type", "application/json")
+			if fail {
+				fmt.Fprint(w, `{"errcode":40001,"errmsg":"invalid credential"}`)
+				return
+			}
+			fmt.Fprint(w, `{"access_token":"token","expires_in":7200}`)
+			return
+		}
+		resp := map[string]string{"return_code": "SUCCESS", "result_code": "SUCCESS", "appid": "wx1", "mch_id": "mch1"}
+		if fail {
+			resp["result_code"] = "FAIL"
+			resp["err_code"] = "ORDERPAID"
+		}
+		resp["sign"] = base.Sign(resp, "mchkey", nil)
+		w.Header().Set("Content-Type", "text/plain")
+		base.FormatMapToXML(w, resp)
+	}))
+	defer srv.Close()
+
+	collector := NewCollector()
+	ctx := &wxcontext.Context{Config: &wxcontext.Config{
+		AppID:        "wx1",
+		MchID:        "mch1",
+		MchAPIKey:    "mchkey",
+		Interceptors: []wxcontext.Interceptor{collector},
+	}}
+	mch := &base.MchBase{Context: ctx}
+	for i := 0; i < 2; i++ {
+		ctx.HTTPGet(srv.URL + "/cgi-bin/token")
+		if _, err := mch.PostXML(srv.URL+"/pay/unifiedorder", map[string]string{"appid": "wx1"}, false); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var buf bytes.Buffer
+	if _, err := collector.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		`wechat_calls_total{kind="outbound",endpoint="/cgi-bin/token",appid="wx1",errcode="40001",result="api_error"} 1`,
+		`wechat_calls_total{kind="outbound",endpoint="/cgi-bin/token",appid="wx1",errcode="0",result="ok"} 1`,
+		`wechat_calls_total{kind="outbound",endpoint="/pay/unifiedorder",appid="wx1",errcode="ORDERPAID",result="api_error"} 1`,
+		`wechat_calls_total{kind="outbound",endpoint="/pay/unifiedorder",appid="wx1",errcode="0",result="ok"} 1`,
+		`wechat_call_duration_seconds_count{kind="outbound",endpoint="/cgi-bin/token",appid="wx1"} 2`,
+		`wechat_call_duration_seconds_count{kind="outbound",endpoint="/pay/unifiedorder",appid="wx1"} 2`,
+		`wechat_call_duration_seconds_bucket{kind="outbound",endpoint="/pay/unifiedorder",appid="wx1",le="+Inf"} 2`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %s in\n%s", want, out)
+		}
+	}
+	if n := strings.Count(out, "wechat_calls_total{"); n != 4 {
+		t.Errorf("want 4 call series, got %d:\n%s", n, out)
+	}
+}
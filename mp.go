@@ -3,14 +3,18 @@
 package gowechat
 
 import (
+	"context"
 	"net/http"
 
 	"github.com/MrCHI/gowechat/mp/account"
 	"github.com/MrCHI/gowechat/mp/bridge"
+	"github.com/MrCHI/gowechat/mp/custom"
 	"github.com/MrCHI/gowechat/mp/jssdk"
 	"github.com/MrCHI/gowechat/mp/material"
 	"github.com/MrCHI/gowechat/mp/menu"
+	"github.com/MrCHI/gowechat/mp/message"
 	"github.com/MrCHI/gowechat/mp/oauth"
+	"github.com/MrCHI/gowechat/mp/quota"
 	"github.com/MrCHI/gowechat/mp/template"
 	"github.com/MrCHI/gowechat/mp/user"
 )
@@ -25,6 +29,11 @@ func (c *MpMgr) GetAccessToken() (string, error) {
 	return c.Context.GetAccessToken()
 }
 
+//GetAccessTokenContext 获取access_token，支持 context
+func (c *MpMgr) GetAccessTokenContext(ctx context.Context) (string, error) {
+	return c.Context.GetAccessTokenContext(ctx)
+}
+
 // GetOauth oauth2网页授权
 func (c *MpMgr) GetOauth() *oauth.Oauth {
 	return oauth.NewOauth(c.Context)
@@ -55,22 +64,37 @@ func (c *MpMgr) GetTemplate() *template.Template {
 	return template.NewTemplate(c.Context)
 }
 
-// GetMsgHandler 消息管理
+// GetCustom 客服消息接口
+func (c *MpMgr) GetCustom() *custom.Custom {
+	return custom.NewCustom(c.Context)
+}
+
+// GetMsgHandler 处理一次推送消息，每个请求调用一次；推荐启动时用 NewMsgServer 创建http.Handler
 func (c *MpMgr) GetMsgHandler(req *http.Request, writer http.ResponseWriter) *bridge.MsgHandler {
-	c.Context.Request = req
-	c.Context.Writer = writer
-	return bridge.NewMsgHandler(c.Context)
+	return bridge.NewMsgHandler(c.Context.WithRequest(req, writer))
 }
 
-//GetPageOAuthHandler 网页授权
+//GetPageOAuthHandler 处理一次网页授权，每个请求调用一次；推荐启动时用 NewPageOAuthServer 创建http.Handler
 func (c *MpMgr) GetPageOAuthHandler(req *http.Request, writer http.ResponseWriter, myURLOfPageOAuthCallback string) *bridge.PageOAuthHandler {
-	c.Context.Request = req
-	c.Context.Writer = writer
-	handler := bridge.NewPageOAuthHandler(c.Context, myURLOfPageOAuthCallback)
-	return handler
+	return bridge.NewPageOAuthHandler(c.Context.WithRequest(req, writer), myURLOfPageOAuthCallback)
+}
+
+// NewMsgServer 处理推送消息的http.Handler，启动时创建一次，可以并发处理请求
+func (c *MpMgr) NewMsgServer(handler message.HandlerFunc) *bridge.MsgServer {
+	return bridge.NewMsgServer(c.Context, handler)
+}
+
+// NewPageOAuthServer 网页授权的http.Handler，启动时创建一次，可以并发处理请求
+func (c *MpMgr) NewPageOAuthServer(myURLOfPageOAuthCallback string) *bridge.PageOAuthServer {
+	return bridge.NewPageOAuthServer(c.Context, myURLOfPageOAuthCallback)
 }
 
 // GetQrcode 带参数的二维码
 func (c *MpMgr) GetQrcode() *account.Qrcode {
 	return account.NewQrcode(c.Context)
 }
+
+// GetQuota 接口调用次数管理
+func (c *MpMgr) GetQuota() *quota.Quota {
+	return quota.NewQuota(c.Context)
+}
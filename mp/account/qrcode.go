@@ -1,7 +1,7 @@
 package account
 
 import (
-	"encoding/json"
+	"context"
 	"errors"
 	"fmt"
 	"net/url"
@@ -34,6 +34,18 @@ const (
 	PermanentQRCodeSceneIDLimit = 100000
 )
 
+//reqQrcode 创建二维码的请求数据，scene_id和scene_str二选一，永久二维码没有expire_seconds
+type reqQrcode struct {
+	ExpireSeconds int    `json:"expire_seconds,omitempty"`
+	ActionName    string `json:"action_name"`
+	ActionInfo    struct {
+		Scene struct {
+			SceneID     uint32 `json:"scene_id,omitempty"`
+			SceneString string `json:"scene_str,omitempty"`
+		} `json:"scene"`
+	} `json:"action_info"`
+}
+
 //QrcodeResult Qrcode Result
 type QrcodeResult struct {
 	Ticket        string `json:"ticket"`                   // 获取的二维码ticket, 凭借此ticket可以在有效时间内换取二维码.
@@ -50,6 +62,11 @@ func (c *QrcodeResult) ImageURL() (imgURL string) {
 //  SceneId:       场景值ID, 为32位非0整型
 //  ExpireSeconds: 二维码有效时间, 以秒为单位.  最大不超过 604800.
 func (c *Qrcode) CreateTemporaryQRCode(SceneID uint32, ExpireSeconds int) (result *QrcodeResult, err error) {
+	return c.CreateTemporaryQRCodeContext(context.Background(), SceneID, ExpireSeconds)
+}
+
+//CreateTemporaryQRCodeContext 同 CreateTemporaryQRCode，支持 context
+func (c *Qrcode) CreateTemporaryQRCodeContext(ctx context.Context, SceneID uint32, ExpireSeconds int) (result *QrcodeResult, err error) {
 	if SceneID == 0 {
 		err = errors.New("SceneId should be greater than 0")
 		return
@@ -58,31 +75,18 @@ func (c *Qrcode) CreateTemporaryQRCode(SceneID uint32, ExpireSeconds int) (resul
 		err = errors.New("ExpireSeconds should be greater than 0")
 		return
 	}
-	var request struct {
-		ExpireSeconds int    `json:"expire_seconds"`
-		ActionName    string `json:"action_name"`
-		ActionInfo    struct {
-			Scene struct {
-				SceneID uint32 `json:"scene_id"`
-			} `json:"scene"`
-		} `json:"action_info"`
-	}
-	request.ExpireSeconds = ExpireSeconds
-	request.ActionName = "QR_SCENE"
-	request.ActionInfo.Scene.SceneID = SceneID
-
-	result = new(QrcodeResult)
-
-	response, err := c.HTTPPostJSONWithAccessToken(qrcodeURL, &request)
-	if err != nil {
-		return nil, err
-	}
-	err = json.Unmarshal(response, result)
-	return
+	req := &reqQrcode{ExpireSeconds: ExpireSeconds, ActionName: "QR_SCENE"}
+	req.ActionInfo.Scene.SceneID = SceneID
+	return base.CallContext[*reqQrcode, *QrcodeResult](ctx, &c.MpBase, qrcodeURL, req)
 }
 
 //CreateTemporaryQRCodeWithSceneString 创建临时二维码 scene_str
 func (c *Qrcode) CreateTemporaryQRCodeWithSceneString(SceneString string, ExpireSeconds int) (result *QrcodeResult, err error) {
+	return c.CreateTemporaryQRCodeWithSceneStringContext(context.Background(), SceneString, ExpireSeconds)
+}
+
+//CreateTemporaryQRCodeWithSceneStringContext 同 CreateTemporaryQRCodeWithSceneString，支持 context
+func (c *Qrcode) CreateTemporaryQRCodeWithSceneStringContext(ctx context.Context, SceneString string, ExpireSeconds int) (result *QrcodeResult, err error) {
 	if SceneString == "" {
 		err = errors.New("SceneString should not be empty")
 		return
@@ -91,81 +95,41 @@ func (c *Qrcode) CreateTemporaryQRCodeWithSceneString(SceneString string, Expire
 		err = errors.New("ExpireSeconds should be greater than 0")
 		return
 	}
-	var request struct {
-		ExpireSeconds int    `json:"expire_seconds"`
-		ActionName    string `json:"action_name"`
-		ActionInfo    struct {
-			Scene struct {
-				SceneString string `json:"scene_str"`
-			} `json:"scene"`
-		} `json:"action_info"`
-	}
-	request.ExpireSeconds = ExpireSeconds
-	request.ActionName = "QR_STR_SCENE"
-	request.ActionInfo.Scene.SceneString = SceneString
-
-	result = new(QrcodeResult)
-
-	response, err := c.HTTPPostJSONWithAccessToken(qrcodeURL, &request)
-	if err != nil {
-		return nil, err
-	}
-	err = json.Unmarshal(response, result)
-	return
+	req := &reqQrcode{ExpireSeconds: ExpireSeconds, ActionName: "QR_STR_SCENE"}
+	req.ActionInfo.Scene.SceneString = SceneString
+	return base.CallContext[*reqQrcode, *QrcodeResult](ctx, &c.MpBase, qrcodeURL, req)
 }
 
 //CreatePermanentQRCode 创建永久二维码
 //  SceneId: 场景值ID, 目前参数只支持1--100000
 func (c *Qrcode) CreatePermanentQRCode(sceneID uint32) (result *QrcodeResult, err error) {
+	return c.CreatePermanentQRCodeContext(context.Background(), sceneID)
+}
+
+//CreatePermanentQRCodeContext 同 CreatePermanentQRCode，支持 context
+func (c *Qrcode) CreatePermanentQRCodeContext(ctx context.Context, sceneID uint32) (result *QrcodeResult, err error) {
 	if sceneID == 0 {
 		err = errors.New("SceneId should be greater than 0")
 		return
 	}
-	var request struct {
-		ActionName string `json:"action_name"`
-		ActionInfo struct {
-			Scene struct {
-				SceneID uint32 `json:"scene_id"`
-			} `json:"scene"`
-		} `json:"action_info"`
-	}
-	request.ActionName = "QR_LIMIT_SCENE"
-	request.ActionInfo.Scene.SceneID = sceneID
-
-	result = new(QrcodeResult)
-
-	response, err := c.HTTPPostJSONWithAccessToken(qrcodeURL, &request)
-	if err != nil {
-		return nil, err
-	}
-	err = json.Unmarshal(response, result)
-	return
+	req := &reqQrcode{ActionName: "QR_LIMIT_SCENE"}
+	req.ActionInfo.Scene.SceneID = sceneID
+	return base.CallContext[*reqQrcode, *QrcodeResult](ctx, &c.MpBase, qrcodeURL, req)
 }
 
 //CreatePermanentQRCodeWithSceneString 创建永久二维码
 //  SceneString: 场景值ID(字符串形式的ID), 字符串类型, 长度限制为1到64
 func (c *Qrcode) CreatePermanentQRCodeWithSceneString(SceneString string) (result *QrcodeResult, err error) {
+	return c.CreatePermanentQRCodeWithSceneStringContext(context.Background(), SceneString)
+}
+
+//CreatePermanentQRCodeWithSceneStringContext 同 CreatePermanentQRCodeWithSceneString，支持 context
+func (c *Qrcode) CreatePermanentQRCodeWithSceneStringContext(ctx context.Context, SceneString string) (result *QrcodeResult, err error) {
 	if SceneString == "" {
 		err = errors.New("SceneString should not be empty")
 		return
 	}
-	var request struct {
-		ActionName string `json:"action_name"`
-		ActionInfo struct {
-			Scene struct {
-				SceneString string `json:"scene_str"`
-			} `json:"scene"`
-		} `json:"action_info"`
-	}
-	request.ActionName = "QR_LIMIT_STR_SCENE"
-	request.ActionInfo.Scene.SceneString = SceneString
-
-	result = new(QrcodeResult)
-
-	response, err := c.HTTPPostJSONWithAccessToken(qrcodeURL, &request)
-	if err != nil {
-		return nil, err
-	}
-	err = json.Unmarshal(response, result)
-	return
+	req := &reqQrcode{ActionName: "QR_LIMIT_STR_SCENE"}
+	req.ActionInfo.Scene.SceneString = SceneString
+	return base.CallContext[*reqQrcode, *QrcodeResult](ctx, &c.MpBase, qrcodeURL, req)
 }
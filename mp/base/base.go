@@ -1,9 +1,8 @@
 package base
 
 import (
+	"context"
 	"fmt"
-	"io/ioutil"
-	"net/http"
 	"strings"
 
 	"github.com/MrCHI/gowechat/util"
@@ -16,76 +15,118 @@ type MpBase struct {
 }
 
 //HTTPGetWithAccessToken 微信公众平台中，自动加上access_token变量的GET调用，
-//如果失败，会清空AccessToken cache, 再试一次
+//如果返回access_token失效的错误，会清空AccessToken cache, 再试一次
 func (c *MpBase) HTTPGetWithAccessToken(url string) (resp []byte, err error) {
+	return c.HTTPGetWithAccessTokenContext(context.Background(), url)
+}
+
+//HTTPGetWithAccessTokenContext 同 HTTPGetWithAccessToken，ctx 同时用于获取access_token和请求
+func (c *MpBase) HTTPGetWithAccessTokenContext(ctx context.Context, url string) (resp []byte, err error) {
+	target := c.ResolveURL(url)
 	retry := 1
 Do:
 	var accessToken string
-	accessToken, err = c.GetAccessToken()
+	accessToken, err = c.GetAccessTokenContext(ctx)
 	if err != nil {
 		return
 	}
 
-	var target = ""
-	if strings.Contains(url, "?") {
-		target = fmt.Sprintf("%s&access_token=%s", url, accessToken)
-	} else {
-		target = fmt.Sprintf("%s?access_token=%s", url, accessToken)
-	}
+	uri := withAccessToken(target, accessToken)
 
-	var response *http.Response
-	response, err = http.Get(target)
+	resp, err = c.HTTPGetContext(ctx, uri)
 	if err != nil {
 		return
 	}
-	defer response.Body.Close()
-
-	resp, err = ioutil.ReadAll(response.Body)
-	err = util.CheckCommonError(resp)
-	if err == util.ErrUnmarshall {
-		return
-	}
-	if err != nil {
-		if retry > 0 {
-			retry--
-			c.CleanAccessTokenCache()
-			goto Do
-		}
-		return
+	err = util.CheckAPIError(url, resp)
+	c.CountCall(ctx, url, err)
+	if util.IsTokenInvalid(err) && retry > 0 {
+		c.Log(ctx, wxcontext.LevelWarn, "access_token失效，重新获取后重试", "endpoint", url, "error", err)
+		retry--
+		ctx = wxcontext.WithRetries(ctx, wxcontext.Retries(ctx)+1)
+		c.CleanAccessTokenCache()
+		goto Do
 	}
 	return
 }
 
 //HTTPPostJSONWithAccessToken post json 自动加上access token, 并retry
 func (c *MpBase) HTTPPostJSONWithAccessToken(url string, obj interface{}) (resp []byte, err error) {
+	return c.HTTPPostJSONWithAccessTokenContext(context.Background(), url, obj)
+}
+
+//HTTPPostJSONWithAccessTokenContext 同 HTTPPostJSONWithAccessToken，支持 context
+func (c *MpBase) HTTPPostJSONWithAccessTokenContext(ctx context.Context, url string, obj interface{}) (resp []byte, err error) {
+	target := c.ResolveURL(url)
 	retry := 1
 Do:
 	var accessToken string
-	accessToken, err = c.GetAccessToken()
+	accessToken, err = c.GetAccessTokenContext(ctx)
 	if err != nil {
 		return
 	}
 
-	var target = ""
-	if strings.Contains(url, "?") {
-		target = fmt.Sprintf("%s&access_token=%s", url, accessToken)
-	} else {
-		target = fmt.Sprintf("%s?access_token=%s", url, accessToken)
+	uri := withAccessToken(target, accessToken)
+
+	resp, err = c.PostJSONContext(ctx, uri, obj)
+	if err != nil {
+		return
+	}
+	err = util.CheckAPIError(url, resp)
+	c.CountCall(ctx, url, err)
+	if util.IsTokenInvalid(err) && retry > 0 {
+		c.Log(ctx, wxcontext.LevelWarn, "access_token失效，重新获取后重试", "endpoint", url, "error", err)
+		retry--
+		ctx = wxcontext.WithRetries(ctx, wxcontext.Retries(ctx)+1)
+		c.CleanAccessTokenCache()
+		goto Do
 	}
+	return
+}
 
-	resp, err = util.PostJSON(target, obj)
+//HTTPPostMultipartWithAccessToken 上传文件，自动加上access_token；字段中没有Reader时，access_token失效会重新获取后再试一次
+func (c *MpBase) HTTPPostMultipartWithAccessToken(url string, fields []util.MultipartFormField) (resp []byte, err error) {
+	return c.HTTPPostMultipartWithAccessTokenContext(context.Background(), url, fields)
+}
 
-	err = util.CheckCommonError(resp)
-	if err == util.ErrUnmarshall {
+//HTTPPostMultipartWithAccessTokenContext 同 HTTPPostMultipartWithAccessToken，支持 context
+func (c *MpBase) HTTPPostMultipartWithAccessTokenContext(ctx context.Context, url string, fields []util.MultipartFormField) (resp []byte, err error) {
+	target := c.ResolveURL(url)
+	retry := 1
+	for _, field := range fields {
+		if field.Reader != nil {
+			//Reader已经读过，不能重发
+			retry = 0
+		}
+	}
+Do:
+	var accessToken string
+	accessToken, err = c.GetAccessTokenContext(ctx)
+	if err != nil {
 		return
 	}
+
+	uri := withAccessToken(target, accessToken)
+
+	resp, err = c.PostMultipartFormContext(ctx, fields, uri)
 	if err != nil {
-		if retry > 0 {
-			retry--
-			c.CleanAccessTokenCache()
-			goto Do
-		}
 		return
 	}
+	err = util.CheckAPIError(url, resp)
+	c.CountCall(ctx, url, err)
+	if util.IsTokenInvalid(err) && retry > 0 {
+		c.Log(ctx, wxcontext.LevelWarn, "access_token失效，重新获取后重试", "endpoint", url, "error", err)
+		retry--
+		ctx = wxcontext.WithRetries(ctx, wxcontext.Retries(ctx)+1)
+		c.CleanAccessTokenCache()
+		goto Do
+	}
 	return
 }
+
+//withAccessToken url中加上access_token参数
+func withAccessToken(url, accessToken string) string {
+	if strings.Contains(url, "?") {
+		return fmt.Sprintf("%s&access_token=%s", url, accessToken)
+	}
+	return fmt.Sprintf("%s?access_token=%s", url, accessToken)
+}
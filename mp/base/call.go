@@ -0,0 +1,52 @@
+package base
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/MrCHI/gowechat/util"
+)
+
+//Call 以POST JSON方式调用接口，自动加上access_token，失效时重新获取后再试一次；
+//errcode不为0时返回 *util.APIError，否则把返回结果解析为Resp。
+//Resp 一般是嵌入了 util.CommonError 的结构体，例如：
+//  res, err := base.CallContext[*reqQuota, resQuota](ctx, &quota.MpBase, getQuotaURL, req)
+func Call[Req, Resp any](c *MpBase, url string, req Req) (Resp, error) {
+	return CallContext[Req, Resp](context.Background(), c, url, req)
+}
+
+//CallContext 同 Call，支持 context
+func CallContext[Req, Resp any](ctx context.Context, c *MpBase, url string, req Req) (res Resp, err error) {
+	var body []byte
+	body, err = c.HTTPPostJSONWithAccessTokenContext(ctx, url, req)
+	if err != nil {
+		return
+	}
+	err = decode(url, body, &res)
+	return
+}
+
+//Get 以GET方式调用接口，其余同 Call
+func Get[Resp any](c *MpBase, url string) (Resp, error) {
+	return GetContext[Resp](context.Background(), c, url)
+}
+
+//GetContext 同 Get，支持 context
+func GetContext[Resp any](ctx context.Context, c *MpBase, url string) (res Resp, err error) {
+	var body []byte
+	body, err = c.HTTPGetWithAccessTokenContext(ctx, url)
+	if err != nil {
+		return
+	}
+	err = decode(url, body, &res)
+	return
+}
+
+//decode 解析返回结果，失败时返回的错误可以用 errors.Is(err, util.ErrUnmarshall) 判断
+func decode(url string, body []byte, v interface{}) error {
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("%s: %w: %v", util.RedactURL(url), util.ErrUnmarshall, err)
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package base_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/MrCHI/gowechat/mp/base"
+	"github.com/MrCHI/gowechat/util"
+	"github.com/MrCHI/gowechat/wxcontext"
+	"github.com/MrCHI/gowechat/wxtest"
+)
+
+func newMpBase(srv *wxtest.Server) *base.MpBase {
+	cfg := srv.Config()
+	ctx := &wxcontext.Context{Config: &cfg}
+	ctx.SetAccessTokenLocker(wxcontext.SharedTokenLock("access_token_" + cfg.AppID))
+	return &base.MpBase{Context: ctx}
+}
+
+func TestCall(t *testing.T) {
+	srv := wxtest.NewServer()
+	defer srv.Close()
+	c := newMpBase(srv)
+
+	type message struct {
+		ToUser     string `json:"touser"`
+		TemplateID string `json:"template_id"`
+	}
+	type result struct {
+		util.CommonError
+		MsgID int64 `json:"msgid"`
+	}
+	res, err := base.Call[message, result](c, "https://api.weixin.qq.com/cgi-bin/message/template/send", message{"openid", "tpl"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.MsgID == 0 {
+		t.Errorf("msgid not decoded: %+v", res)
+	}
+
+	_, err = base.Call[message, result](c, "https://api.weixin.qq.com/cgi-bin/message/template/send", message{ToUser: "openid"})
+	if util.ErrCodeOf(err) != 40037 {
+		t.Errorf("want errcode 40037, got %v", err)
+	}
+
+	if err := srv.SetUser("openid", map[string]interface{}{"openid": "openid", "subscribe": 1}); err != nil {
+		t.Fatal(err)
+	}
+	srv.ExpireTokens()
+	type info struct {
+		util.CommonError
+		OpenID string `json:"openid"`
+	}
+	u, err := base.Get[*info](c, "https://api.weixin.qq.com/cgi-bin/user/info?openid=openid")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.OpenID != "openid" {
+		t.Errorf("unexpected user %+v", u)
+	}
+	if n := srv.TokenRequests(); n != 2 {
+		t.Errorf("token requested %d times, want 2", n)
+	}
+
+	_, err = base.Get[[]string](c, "https://api.weixin.qq.com/cgi-bin/user/info?openid=openid")
+	if !errors.Is(err, util.ErrUnmarshall) {
+		t.Errorf("want ErrUnmarshall, got %v", err)
+	}
+}
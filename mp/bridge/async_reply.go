@@ -0,0 +1,79 @@
+package bridge
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"time"
+
+	"github.com/MrCHI/gowechat/mp/custom"
+	"github.com/MrCHI/gowechat/mp/message"
+	"github.com/MrCHI/gowechat/wxcontext"
+)
+
+//handlerResult 处理方法的返回值，panic时记录panic的值
+type handlerResult struct {
+	reply    *message.Reply
+	panicked interface{}
+	stack    []byte
+}
+
+//callHandler 调用处理方法，超过 MsgReplyTimeout 时inTime为false，
+//处理方法在后台继续执行，返回的回复通过客服消息发给用户
+func (srv *MsgHandler) callHandler(ctx context.Context) (reply *message.Reply, inTime bool) {
+	if srv.handler == nil {
+		return nil, true
+	}
+	timeout := srv.MsgReplyTimeout
+	if timeout == 0 {
+		timeout = wxcontext.DefaultMsgReplyTimeout
+	}
+	if timeout < 0 {
+		return srv.handler(ctx, &srv.requestMsg), true
+	}
+
+	//请求结束后ctx会被取消，处理方法可能需要继续执行
+	msg := srv.requestMsg
+	handler := srv.handler
+	done := make(chan handlerResult, 1)
+	go func() {
+		var res handlerResult
+		defer func() {
+			if e := recover(); e != nil {
+				res = handlerResult{panicked: e, stack: debug.Stack()}
+			}
+			done <- res
+		}()
+		res.reply = handler(wxcontext.Detach(ctx), &msg)
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case res := <-done:
+		if res.panicked != nil {
+			panic(res.panicked)
+		}
+		return res.reply, true
+	case <-timer.C:
+	}
+
+	srv.Log(ctx, wxcontext.LevelWarn, "处理消息超时，改为客服消息回复",
+		"msg_type", msg.MsgType, "event", msg.Event, "timeout", timeout)
+	go srv.replyLater(wxcontext.Detach(ctx), msg.FromUserName, done)
+	return nil, false
+}
+
+//replyLater 等待处理方法返回，把回复作为客服消息发给用户
+func (srv *MsgHandler) replyLater(ctx context.Context, toUser string, done <-chan handlerResult) {
+	res := <-done
+	switch {
+	case res.panicked != nil:
+		srv.Log(ctx, wxcontext.LevelError, "处理消息panic", "error", fmt.Sprint(res.panicked), "stack", string(res.stack))
+	case res.reply == nil:
+	default:
+		if err := custom.NewCustom(srv.Context).SendReplyContext(ctx, toUser, res.reply); err != nil {
+			srv.Log(ctx, wxcontext.LevelError, "客服消息回复失败", "to_user", toUser, "error", err)
+		}
+	}
+}
@@ -0,0 +1,17 @@
+//Package beegobridge 将 MsgServer、PageOAuthServer 等 http.Handler 接入 beego
+//
+//单独成包，不使用beego的项目不会引入beego依赖
+package beegobridge
+
+import (
+	"net/http"
+
+	"github.com/astaxie/beego/context"
+)
+
+//Handler 转换为 beego 的 FilterFunc，例如 beego.Any("/wx", beegobridge.Handler(mp.NewMsgServer(r.Serve)))
+func Handler(h http.Handler) func(ctx *context.Context) {
+	return func(ctx *context.Context) {
+		h.ServeHTTP(ctx.ResponseWriter, ctx.Request)
+	}
+}
@@ -0,0 +1,84 @@
+package bridge
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/MrCHI/gowechat/cache"
+	"github.com/MrCHI/gowechat/mp/message"
+	"github.com/MrCHI/gowechat/wxcontext"
+)
+
+const (
+	//dedupPending 第一次推送还在处理中
+	dedupPending = "pending"
+	//dedupDonePrefix 处理完毕，后面是回复的明文xml（没有回复时为空）
+	dedupDonePrefix = "done:"
+)
+
+//dedupWindow 去重时间，小于0时不去重
+func (srv *MsgHandler) dedupWindow() time.Duration {
+	if srv.MsgDedupWindow == 0 {
+		return wxcontext.DefaultMsgDedupWindow
+	}
+	return srv.MsgDedupWindow
+}
+
+//dedupKey 消息的去重key，普通消息使用MsgId，事件使用FromUserName+CreateTime+Event
+func dedupKey(appID string, msg *message.MixMessage) string {
+	if msg.MsgID != 0 {
+		return fmt.Sprintf("msg_dedup_%s_%d", appID, msg.MsgID)
+	}
+	return fmt.Sprintf("msg_dedup_%s_%s_%d_%s", appID, msg.FromUserName, msg.CreateTime, msg.Event)
+}
+
+//claimMessage 记录收到的推送，第一次收到时first为true；
+//重复的推送返回第一次处理的回复，第一次仍在处理或没有回复时为nil。
+//不去重或者存储出错时key为空，first为true
+func (srv *MsgHandler) claimMessage(ctx context.Context) (key string, first bool, cached []byte) {
+	window := srv.dedupWindow()
+	if window < 0 {
+		return "", true, nil
+	}
+	key = dedupKey(srv.AppID, &srv.requestMsg)
+
+	var err error
+	if locker, ok := srv.Cache.(cache.Locker); ok {
+		first, err = locker.PutIfAbsent(key, dedupPending, window)
+	} else if first = !srv.Cache.IsExist(key); first {
+		//没有实现Locker的存储，多个进程同时收到时可能都会处理
+		err = srv.Cache.Put(key, dedupPending, window)
+	}
+	if err != nil {
+		srv.Log(ctx, wxcontext.LevelWarn, "记录推送失败，不去重", "key", key, "error", err)
+		return "", true, nil
+	}
+	if first {
+		return
+	}
+
+	srv.Log(ctx, wxcontext.LevelInfo, "重复的推送，不再处理", "key", key)
+	if val := cache.GetString(srv.Cache, key); strings.HasPrefix(val, dedupDonePrefix) {
+		cached = []byte(val[len(dedupDonePrefix):])
+	}
+	return
+}
+
+//finishMessage 保存处理结果，用于回复之后重复的推送
+func (srv *MsgHandler) finishMessage(ctx context.Context, key string) {
+	if key == "" {
+		return
+	}
+	if err := srv.Cache.Put(key, dedupDonePrefix+string(srv.responseRawXMLMsg), srv.dedupWindow()); err != nil {
+		srv.Log(ctx, wxcontext.LevelWarn, "保存推送的处理结果失败", "key", key, "error", err)
+	}
+}
+
+//releaseMessage 处理失败时删除记录，微信重试时重新处理
+func (srv *MsgHandler) releaseMessage(key string) {
+	if key != "" {
+		srv.Cache.Delete(key)
+	}
+}
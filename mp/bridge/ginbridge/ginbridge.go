@@ -0,0 +1,17 @@
+//Package ginbridge 将 MsgServer、PageOAuthServer 等 http.Handler 接入 gin
+//
+//单独成包，不使用gin的项目不会引入gin依赖
+package ginbridge
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+//Handler 转换为 gin.HandlerFunc，例如 router.Any("/wx", ginbridge.Handler(mp.NewMsgServer(r.Serve)))
+func Handler(h http.Handler) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		h.ServeHTTP(c.Writer, c.Request)
+	}
+}
@@ -1,6 +1,7 @@
 package bridge
 
 import (
+	"context"
 	"encoding/xml"
 	"errors"
 	"fmt"
@@ -19,7 +20,7 @@ import (
 type MsgHandler struct {
 	*wxcontext.Context
 
-	handleMessageFunc func(message.MixMessage) *message.Reply
+	handler message.HandlerFunc
 
 	requestRawXMLMsg  []byte
 	requestMsg        message.MixMessage
@@ -35,7 +36,6 @@ type MsgHandler struct {
 //NewMsgHandler init
 func NewMsgHandler(context *wxcontext.Context) *MsgHandler {
 	srv := new(MsgHandler)
-	//fmt.Println("NewMsgHandler:", srv)
 	srv.Context = context
 	return srv
 }
@@ -59,22 +59,60 @@ func (srv *MsgHandler) Handle() error {
 	//Request is POST
 	//微信公众平台将消息post到服务器上
 	if strings.ToLower(srv.Context.Request.Method) == "post" {
-		replyMsg, err := srv.handleRequest()
-		if err != nil {
-			return err
-		}
-		//debug
-		// fmt.Println("request msg = ", string(srv.requestRawXMLMsg))
-		err = srv.buildResponse(replyMsg)
-		if err == nil {
-			srv.Send()
-		} else {
-			return err
+		ctx, info := srv.StartCall(srv.RequestContext(), wxcontext.CallInbound, "message")
+		err := srv.handlePost(ctx)
+		if info != nil && srv.requestMsg.MsgType != "" {
+			info.Endpoint = "message/" + string(srv.requestMsg.MsgType)
+			if srv.requestMsg.Event != "" {
+				info.Endpoint += "/" + string(srv.requestMsg.Event)
+			}
 		}
+		srv.EndCall(ctx, info, err)
+		return err
 	}
 	return nil
 }
 
+//handlePost 处理微信post过来的消息并回复，重复的推送不再调用处理方法
+func (srv *MsgHandler) handlePost(ctx context.Context) (err error) {
+	var mixMessage message.MixMessage
+	if mixMessage, err = srv.getMessage(); err != nil {
+		return
+	}
+	srv.requestMsg = mixMessage
+	srv.Log(ctx, wxcontext.LevelDebug, "request msg", "body", string(srv.requestRawXMLMsg))
+
+	key, first, cached := srv.claimMessage(ctx)
+	if !first {
+		if srv.MsgDedupReplay && len(cached) > 0 {
+			srv.responseRawXMLMsg = cached
+			return srv.Send()
+		}
+		return srv.sendSuccess()
+	}
+	finished := false
+	defer func() {
+		if !finished {
+			//处理失败时允许微信重试
+			srv.releaseMessage(key)
+		}
+	}()
+
+	replyMsg, inTime := srv.callHandler(ctx)
+	if !inTime {
+		//先回复success，处理结果之后通过客服消息发送
+		finished = true
+		srv.finishMessage(ctx, key)
+		return srv.sendSuccess()
+	}
+	if err = srv.buildResponse(replyMsg); err != nil {
+		return
+	}
+	finished = true
+	srv.finishMessage(ctx, key)
+	return srv.Send()
+}
+
 //Validate 校验请求是否合法
 func (srv *MsgHandler) Validate() bool {
 	timestamp := srv.Query("timestamp")
@@ -83,79 +121,85 @@ func (srv *MsgHandler) Validate() bool {
 	return signature == util.Signature(srv.Token, timestamp, nonce)
 }
 
-//HandleRequest 处理微信的请求
-func (srv *MsgHandler) handleRequest() (reply *message.Reply, err error) {
-	//set isSafeMode
-	srv.isSafeMode = false
-	encryptType := srv.Query("encrypt_type")
-	if encryptType == "aes" {
-		srv.isSafeMode = true
-	}
-
-	var msg interface{}
-	msg, err = srv.getMessage()
+//getMessage 解析微信推送的消息并按 EncryptMode 校验；推送中带密文时解密使用密文（校验msg_signature），并以密文回复
+func (srv *MsgHandler) getMessage() (message.MixMessage, error) {
+	body, err := ioutil.ReadAll(srv.Request.Body)
 	if err != nil {
-		return
+		return message.MixMessage{}, fmt.Errorf("从body中解析xml失败, err=%v", err)
 	}
-	mixMessage, success := msg.(message.MixMessage)
-	if !success {
-		err = errors.New("消息类型转换失败")
+	if !srv.Validate() {
+		return message.MixMessage{}, fmt.Errorf("消息不合法，验证签名失败")
 	}
-	srv.requestMsg = mixMessage
-	reply = srv.handleMessageFunc(mixMessage)
-	return
-}
 
-//getMessage 解析微信返回的消息
-func (srv *MsgHandler) getMessage() (interface{}, error) {
-	var rawXMLMsgBytes []byte
-	var err error
-	if srv.isSafeMode {
-		var encryptedXMLMsg message.EncryptedXMLMsg
-		if err := xml.NewDecoder(srv.Request.Body).Decode(&encryptedXMLMsg); err != nil {
-			return nil, fmt.Errorf("从body中解析xml失败,err=%v", err)
-		}
-
-		//验证消息签名
-		timestamp := srv.Query("timestamp")
-		srv.timestamp, err = strconv.ParseInt(timestamp, 10, 32)
-		if err != nil {
-			return nil, err
+	//明文推送同样能解析，Encrypt为空
+	var encryptedXMLMsg message.EncryptedXMLMsg
+	if err := xml.Unmarshal(body, &encryptedXMLMsg); err != nil {
+		return message.MixMessage{}, fmt.Errorf("从body中解析xml失败,err=%v", err)
+	}
+	//是否解密只看消息中有没有Encrypt，不能只依赖url参数
+	encrypted := encryptedXMLMsg.EncryptedMsg != ""
+	if srv.Query("encrypt_type") == "aes" && !encrypted {
+		return message.MixMessage{}, errors.New("encrypt_type为aes，但消息中没有Encrypt")
+	}
+	switch srv.EncryptMode {
+	case wxcontext.EncryptModePlain:
+		if encrypted {
+			return message.MixMessage{}, errors.New("EncryptMode为plain，不接受加密的消息")
 		}
-		nonce := srv.Query("nonce")
-		srv.nonce = nonce
-		msgSignature := srv.Query("msg_signature")
-		msgSignatureGen := util.Signature(srv.Token, timestamp, nonce, encryptedXMLMsg.EncryptedMsg)
-		if msgSignature != msgSignatureGen {
-			return nil, fmt.Errorf("消息不合法，验证签名失败")
+	case wxcontext.EncryptModeSafe:
+		if !encrypted || srv.Query("msg_signature") == "" {
+			return message.MixMessage{}, errors.New("EncryptMode为safe，不接受没有Encrypt或msg_signature的消息")
 		}
+	}
 
-		//解密
-		srv.random, rawXMLMsgBytes, err = util.DecryptMsg(srv.AppID, encryptedXMLMsg.EncryptedMsg, srv.EncodingAESKey)
-		if err != nil {
-			return nil, fmt.Errorf("消息解密失败, err=%v", err)
-		}
-	} else {
-		rawXMLMsgBytes, err = ioutil.ReadAll(srv.Request.Body)
-		if err != nil {
-			return nil, fmt.Errorf("从body中解析xml失败, err=%v", err)
+	srv.isSafeMode = encrypted
+	rawXMLMsgBytes := body
+	if encrypted {
+		if rawXMLMsgBytes, err = srv.decryptMessage(encryptedXMLMsg.EncryptedMsg); err != nil {
+			return message.MixMessage{}, err
 		}
 	}
-
 	srv.requestRawXMLMsg = rawXMLMsgBytes
 
 	return srv.parseRequestMessage(rawXMLMsgBytes)
 }
 
-func (srv *MsgHandler) parseRequestMessage(rawXMLMsgBytes []byte) (msg message.MixMessage, err error) {
-	msg = message.MixMessage{}
-	err = xml.Unmarshal(rawXMLMsgBytes, &msg)
+//decryptMessage 验证msg_signature并解密
+func (srv *MsgHandler) decryptMessage(encryptedMsg string) (rawXMLMsgBytes []byte, err error) {
+	timestamp := srv.Query("timestamp")
+	srv.timestamp, err = strconv.ParseInt(timestamp, 10, 32)
+	if err != nil {
+		return nil, err
+	}
+	nonce := srv.Query("nonce")
+	srv.nonce = nonce
+	msgSignature := srv.Query("msg_signature")
+	msgSignatureGen := util.Signature(srv.Token, timestamp, nonce, encryptedMsg)
+	if msgSignature != msgSignatureGen {
+		return nil, fmt.Errorf("消息不合法，验证签名失败")
+	}
+
+	srv.random, rawXMLMsgBytes, err = util.DecryptMsg(srv.AppID, encryptedMsg, srv.EncodingAESKey)
+	if err != nil {
+		return nil, fmt.Errorf("消息解密失败, err=%v", err)
+	}
 	return
 }
 
+func (srv *MsgHandler) parseRequestMessage(rawXMLMsgBytes []byte) (msg message.MixMessage, err error) {
+	return message.ParseMixMessage(rawXMLMsgBytes)
+}
+
 //SetHandleMessageFunc 设置用户自定义的回调方法
 func (srv *MsgHandler) SetHandleMessageFunc(handler func(message.MixMessage) *message.Reply) {
-	srv.handleMessageFunc = handler
+	srv.handler = func(_ context.Context, msg *message.MixMessage) *message.Reply {
+		return handler(*msg)
+	}
+}
+
+//SetHandler 设置消息处理方法，ctx为当前请求的context，一般传入 message.Router 的 Serve
+func (srv *MsgHandler) SetHandler(handler message.HandlerFunc) {
+	srv.handler = handler
 }
 
 func (srv *MsgHandler) buildResponse(reply *message.Reply) (err error) {
@@ -208,10 +252,16 @@ func (srv *MsgHandler) buildResponse(reply *message.Reply) (err error) {
 	return
 }
 
+//sendSuccess 回复success，微信不会重试，也不会提示用户“该公众号暂时无法提供服务”
+func (srv *MsgHandler) sendSuccess() error {
+	srv.String("success")
+	return nil
+}
+
 //Send 将自定义的消息发送
 func (srv *MsgHandler) Send() (err error) {
 	replyMsg := srv.responseMsg
-	if srv.isSafeMode {
+	if srv.isSafeMode && len(srv.responseRawXMLMsg) > 0 {
 		//安全模式下对消息进行加密
 		var encryptedMsg []byte
 		encryptedMsg, err = util.EncryptMsg(srv.random, srv.responseRawXMLMsg, srv.AppID, srv.EncodingAESKey)
@@ -231,6 +281,10 @@ func (srv *MsgHandler) Send() (err error) {
 	}
 	if replyMsg != nil {
 		srv.XML(replyMsg)
+	} else if len(srv.responseRawXMLMsg) > 0 {
+		//重复推送时回复的缓存
+		srv.Writer.Header().Set("Content-Type", "application/xml; charset=utf-8")
+		srv.Render(srv.responseRawXMLMsg)
 	}
 	return
 }
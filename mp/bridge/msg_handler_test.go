@@ -0,0 +1,339 @@
+package bridge_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/MrCHI/gowechat"
+	"github.com/MrCHI/gowechat/mp/message"
+	"github.com/MrCHI/gowechat/util"
+	"github.com/MrCHI/gowechat/wxcontext"
+	"github.com/MrCHI/gowechat/wxtest"
+)
+
+func echoHandler(mp *gowechat.MpMgr, errs *[]error) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h := mp.GetMsgHandler(r, w)
+		h.SetHandleMessageFunc(func(msg message.MixMessage) *message.Reply {
+			return &message.Reply{MsgType: message.MsgTypeText, MsgData: message.NewText("echo: " + msg.Content)}
+		})
+		if err := h.Handle(); err != nil {
+			*errs = append(*errs, err)
+		}
+	})
+}
+
+func TestEncryptMode(t *testing.T) {
+	tests := []struct {
+		cfg       wxcontext.EncryptMode
+		push      wxcontext.EncryptMode
+		encrypted bool //回复是否加密
+		fail      bool
+	}{
+		{"", wxcontext.EncryptModePlain, false, false},
+		{"", wxcontext.EncryptModeSafe, true, false},
+		{wxcontext.EncryptModePlain, wxcontext.EncryptModePlain, false, false},
+		{wxcontext.EncryptModePlain, wxcontext.EncryptModeSafe, false, true},
+		{wxcontext.EncryptModeCompatible, wxcontext.EncryptModePlain, false, false},
+		{wxcontext.EncryptModeCompatible, wxcontext.EncryptModeCompatible, true, false},
+		{wxcontext.EncryptModeCompatible, wxcontext.EncryptModeSafe, true, false},
+		{wxcontext.EncryptModeSafe, wxcontext.EncryptModePlain, false, true},
+		{wxcontext.EncryptModeSafe, wxcontext.EncryptModeCompatible, true, false},
+	}
+	srv := wxtest.NewServer()
+	defer srv.Close()
+	for _, tt := range tests {
+		cfg := srv.Config()
+		cfg.EncryptMode = tt.cfg
+		mp, err := gowechat.NewWechat(cfg).MpMgr()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var errs []error
+		rec := srv.PushMessageMode(echoHandler(mp, &errs), srv.TextMessage("user1", "hello"), tt.push)
+		if tt.fail {
+			if len(errs) == 0 {
+				t.Errorf("%q/%s: expected error", tt.cfg, tt.push)
+			}
+			continue
+		}
+		if len(errs) != 0 {
+			t.Errorf("%q/%s: %v", tt.cfg, tt.push, errs)
+			continue
+		}
+		raw := rec.Body.Bytes()
+		if tt.encrypted {
+			if raw, err = srv.DecryptReply(raw); err != nil {
+				t.Errorf("%q/%s: %v", tt.cfg, tt.push, err)
+				continue
+			}
+		}
+		if !strings.Contains(string(raw), "echo: hello") {
+			t.Errorf("%q/%s: unexpected reply %s", tt.cfg, tt.push, raw)
+		}
+	}
+}
+
+func TestEncryptModeMismatch(t *testing.T) {
+	srv := wxtest.NewServer()
+	defer srv.Close()
+	//明文字段和密文内容不同，根据回复判断是否解密
+	plain := srv.TextMessage("user1", "plain")
+	encrypted, err := util.EncryptMsg([]byte(util.RandomStr(16)), []byte(srv.TextMessage("user1", "secret")), srv.AppID, srv.EncodingAESKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	withEncrypt := strings.Replace(plain, "</xml>", "<Encrypt><![CDATA["+string(encrypted)+"]]></Encrypt></xml>", 1)
+
+	tests := []struct {
+		name     string
+		cfg      wxcontext.EncryptMode
+		body     string
+		aes      bool //url中带encrypt_type=aes
+		msgSign  bool //url中带msg_signature
+		want     string
+		wantFail bool
+	}{
+		{name: "safe without query", cfg: wxcontext.EncryptModeSafe, body: withEncrypt, wantFail: true},
+		{name: "safe without msg_signature", cfg: wxcontext.EncryptModeSafe, body: withEncrypt, aes: true, wantFail: true},
+		{name: "safe plaintext with query", cfg: wxcontext.EncryptModeSafe, body: plain, aes: true, msgSign: true, wantFail: true},
+		{name: "safe without encrypt_type", cfg: wxcontext.EncryptModeSafe, body: withEncrypt, msgSign: true, want: "echo: secret"},
+		{name: "compatible without encrypt_type", cfg: wxcontext.EncryptModeCompatible, body: withEncrypt, msgSign: true, want: "echo: secret"},
+		{name: "compatible without msg_signature", cfg: wxcontext.EncryptModeCompatible, body: withEncrypt, wantFail: true},
+		{name: "default without encrypt_type", cfg: "", body: withEncrypt, msgSign: true, want: "echo: secret"},
+		{name: "plain with Encrypt", cfg: wxcontext.EncryptModePlain, body: withEncrypt, wantFail: true},
+		{name: "compatible plaintext", cfg: wxcontext.EncryptModeCompatible, body: plain, want: "echo: plain"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := srv.Config()
+			cfg.EncryptMode = tt.cfg
+			mp, err := gowechat.NewWechat(cfg).MpMgr()
+			if err != nil {
+				t.Fatal(err)
+			}
+			timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+			query := url.Values{}
+			query.Set("timestamp", timestamp)
+			query.Set("nonce", "nonce")
+			query.Set("signature", util.Signature(srv.Token, timestamp, "nonce"))
+			if tt.aes {
+				query.Set("encrypt_type", "aes")
+			}
+			if tt.msgSign {
+				query.Set("msg_signature", util.Signature(srv.Token, timestamp, "nonce", string(encrypted)))
+			}
+			var errs []error
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/?"+query.Encode(), strings.NewReader(tt.body))
+			echoHandler(mp, &errs).ServeHTTP(rec, req)
+			if tt.wantFail {
+				if len(errs) == 0 || strings.Contains(rec.Body.String(), "echo") {
+					t.Errorf("want error, got reply %s", rec.Body.String())
+				}
+				return
+			}
+			if len(errs) != 0 {
+				t.Fatal(errs)
+			}
+			raw := rec.Body.Bytes()
+			if tt.want == "echo: secret" {
+				if raw, err = srv.DecryptReply(raw); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if !strings.Contains(string(raw), tt.want) {
+				t.Errorf("unexpected reply %s, want %s", raw, tt.want)
+			}
+		})
+	}
+}
+
+func TestBadSignature(t *testing.T) {
+	srv := wxtest.NewServer()
+	defer srv.Close()
+	mp, err := gowechat.NewWechat(srv.Config()).MpMgr()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var errs []error
+	req := httptest.NewRequest(http.MethodPost, "/?timestamp=1&nonce=x&signature=bad", strings.NewReader(srv.TextMessage("user1", "hello")))
+	rec := httptest.NewRecorder()
+	echoHandler(mp, &errs).ServeHTTP(rec, req)
+	if len(errs) == 0 || strings.Contains(rec.Body.String(), "echo") {
+		t.Errorf("unsigned message accepted: %v %s", errs, rec.Body.String())
+	}
+}
+
+func TestDuplicatePush(t *testing.T) {
+	srv := wxtest.NewServer()
+	defer srv.Close()
+	for _, replay := range []bool{false, true} {
+		cfg := srv.Config()
+		cfg.MsgDedupReplay = replay
+		mp, err := gowechat.NewWechat(cfg).MpMgr()
+		if err != nil {
+			t.Fatal(err)
+		}
+		calls := 0
+		handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			h := mp.GetMsgHandler(r, w)
+			h.SetHandleMessageFunc(func(msg message.MixMessage) *message.Reply {
+				calls++
+				return &message.Reply{MsgType: message.MsgTypeText, MsgData: message.NewText("echo: " + msg.Content)}
+			})
+			if err := h.Handle(); err != nil {
+				t.Error(err)
+			}
+		})
+
+		msg := srv.TextMessage("user1", "hello")
+		first := srv.PushMessage(handler, msg, true)
+		retry := srv.PushMessage(handler, msg, true)
+		if calls != 1 {
+			t.Errorf("replay=%v: handler called %d times, want 1", replay, calls)
+		}
+		if raw, err := srv.DecryptReply(first.Body.Bytes()); err != nil || !strings.Contains(string(raw), "echo: hello") {
+			t.Errorf("replay=%v: unexpected first reply %s %v", replay, raw, err)
+		}
+		if !replay {
+			if retry.Body.String() != "success" || !strings.HasPrefix(retry.Header().Get("Content-Type"), "text/plain") {
+				t.Errorf("retry should get success, got %q %q", retry.Header().Get("Content-Type"), retry.Body.String())
+			}
+			continue
+		}
+		if raw, err := srv.DecryptReply(retry.Body.Bytes()); err != nil || !strings.Contains(string(raw), "echo: hello") {
+			t.Errorf("retry should get the cached reply, got %s %v", raw, err)
+		}
+
+		event := "<xml><ToUserName><![CDATA[gh_x]]></ToUserName><FromUserName><![CDATA[user1]]></FromUserName>" +
+			"<CreateTime>1600000000</CreateTime><MsgType><![CDATA[event]]></MsgType><Event><![CDATA[subscribe]]></Event></xml>"
+		srv.PushMessage(handler, event, false)
+		if rec := srv.PushMessage(handler, event, false); !strings.Contains(rec.Body.String(), "echo: ") {
+			t.Errorf("retry should get the cached plaintext reply, got %s", rec.Body.String())
+		}
+		if calls != 2 {
+			t.Errorf("event handled %d times, want 1", calls-1)
+		}
+	}
+}
+
+func TestSlowHandlerRepliesByCustomMessage(t *testing.T) {
+	srv := wxtest.NewServer()
+	defer srv.Close()
+	cfg := srv.Config()
+	cfg.MsgReplyTimeout = 50 * time.Millisecond
+	mp, err := gowechat.NewWechat(cfg).MpMgr()
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h := mp.GetMsgHandler(r, w)
+		h.SetHandleMessageFunc(func(msg message.MixMessage) *message.Reply {
+			if msg.Content == "slow" {
+				time.Sleep(200 * time.Millisecond)
+			}
+			return &message.Reply{MsgType: message.MsgTypeText, MsgData: message.NewText("echo: " + msg.Content)}
+		})
+		if err := h.Handle(); err != nil {
+			t.Error(err)
+		}
+	})
+
+	if rec := srv.PushMessage(handler, srv.TextMessage("user1", "fast"), false); !strings.Contains(rec.Body.String(), "echo: fast") {
+		t.Errorf("fast handler should reply passively, got %s", rec.Body.String())
+	}
+	if rec := srv.PushMessage(handler, srv.TextMessage("user1", "slow"), false); rec.Body.String() != "success" ||
+		!strings.HasPrefix(rec.Header().Get("Content-Type"), "text/plain") {
+		t.Errorf("slow handler should get success, got %q %q", rec.Header().Get("Content-Type"), rec.Body.String())
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for len(srv.CustomMessages()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	msgs := srv.CustomMessages()
+	if len(msgs) != 1 || !strings.Contains(string(msgs[0]), `"touser":"user1"`) || !strings.Contains(string(msgs[0]), "echo: slow") {
+		t.Errorf("unexpected custom messages %s", msgs)
+	}
+}
+
+func TestMsgServerConcurrent(t *testing.T) {
+	srv := wxtest.NewServer()
+	defer srv.Close()
+	mp, err := gowechat.NewWechat(srv.Config()).MpMgr()
+	if err != nil {
+		t.Fatal(err)
+	}
+	router := message.NewRouter()
+	router.Text(func(ctx context.Context, msg *message.MixMessage) *message.Reply {
+		return &message.Reply{MsgType: message.MsgTypeText, MsgData: message.NewText("echo: " + msg.Content)}
+	})
+	handler := mp.NewMsgServer(router.Serve)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			user := fmt.Sprintf("user%d", i)
+			msg := fmt.Sprintf("<xml><ToUserName><![CDATA[%s]]></ToUserName><FromUserName><![CDATA[%s]]></FromUserName>"+
+				"<CreateTime>1600000000</CreateTime><MsgType><![CDATA[text]]></MsgType><Content><![CDATA[%s]]></Content>"+
+				"<MsgId>%d</MsgId></xml>", srv.OriginalID, user, user, i+1)
+			encrypt := i%2 == 0
+			raw := srv.PushMessage(handler, msg, encrypt).Body.Bytes()
+			if encrypt {
+				var err error
+				if raw, err = srv.DecryptReply(raw); err != nil {
+					t.Errorf("%s: %v", user, err)
+					return
+				}
+			}
+			if !strings.Contains(string(raw), "<ToUserName>"+user+"</ToUserName>") ||
+				!strings.Contains(string(raw), "echo: "+user+"<") {
+				t.Errorf("%s got reply %s", user, raw)
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestRequestLogRedacted(t *testing.T) {
+	const ticket = "gQH47joAAAAAAAAAASxodHRwOi8vd2VpeGluLnFxLmNvbS9xL2taZ2Z3TVRtNzJXV1Brb3ZhYmJJAAIEZ23sUwMEmm3sUw=="
+	srv := wxtest.NewServer()
+	defer srv.Close()
+	var logs bytes.Buffer
+	cfg := srv.Config()
+	cfg.Logger = wxcontext.NewStdLogger(log.New(&logs, "", 0), wxcontext.LevelDebug)
+	mp, err := gowechat.NewWechat(cfg).MpMgr()
+	if err != nil {
+		t.Fatal(err)
+	}
+	router := message.NewRouter()
+	router.Use(message.Logging(cfg.Logger))
+	event := "<xml><ToUserName><![CDATA[gh_x]]></ToUserName><FromUserName><![CDATA[user1]]></FromUserName>" +
+		"<CreateTime>1600000000</CreateTime><MsgType><![CDATA[event]]></MsgType><Event><![CDATA[subscribe]]></Event>" +
+		"<EventKey><![CDATA[qrscene_123456]]></EventKey><Ticket><![CDATA[" + ticket + "]]></Ticket></xml>"
+	srv.PushMessage(mp.NewMsgServer(router.Serve), event, true)
+
+	if !strings.Contains(logs.String(), "request msg") {
+		t.Fatalf("request not logged:\n%s", logs.String())
+	}
+	if strings.Contains(logs.String(), ticket) {
+		t.Errorf("ticket leaked in logs:\n%s", logs.String())
+	}
+	//EventKey不是密钥，请求体和Logging中都应该保留
+	if !strings.Contains(logs.String(), "<EventKey><![CDATA[qrscene_123456]]></EventKey>") ||
+		!strings.Contains(logs.String(), "event_key=qrscene_123456") {
+		t.Errorf("event key should be kept in logs:\n%s", logs.String())
+	}
+}
@@ -0,0 +1,29 @@
+package bridge
+
+import (
+	"net/http"
+
+	"github.com/MrCHI/gowechat/mp/message"
+	"github.com/MrCHI/gowechat/wxcontext"
+)
+
+//MsgServer 处理微信推送消息的 http.Handler，启动时创建一次，可以并发处理请求。
+//每个请求使用独立的 MsgHandler，不会修改共用的Context
+type MsgServer struct {
+	context *wxcontext.Context
+	handler message.HandlerFunc
+}
+
+//NewMsgServer 实例化，handler一般传入 message.Router 的 Serve
+func NewMsgServer(context *wxcontext.Context, handler message.HandlerFunc) *MsgServer {
+	return &MsgServer{context: context, handler: handler}
+}
+
+//ServeHTTP 实现http.Handler，GET请求用于接入验证，POST请求为推送的消息；处理失败时输出Warn日志
+func (s *MsgServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h := NewMsgHandler(s.context.WithRequest(r, w))
+	h.SetHandler(s.handler)
+	if err := h.Handle(); err != nil {
+		s.context.Log(r.Context(), wxcontext.LevelWarn, "处理推送消息失败", "error", err)
+	}
+}
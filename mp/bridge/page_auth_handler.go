@@ -77,7 +77,7 @@ func (c *PageOAuthHandler) Handle() (err error) {
 	c.myURLNeedPageOAuth = c.Query("target")
 	if code != "" {
 		var acsTkn oauth.ResAccessToken
-		acsTkn, err = c.GetUserAccessToken(code)
+		acsTkn, err = c.GetUserAccessTokenContext(c.Request.Context(), code)
 		if err != nil {
 			return
 		}
@@ -91,7 +91,7 @@ func (c *PageOAuthHandler) Handle() (err error) {
 			return
 		}
 		//用 user模块的，没用oauth模板，可以获得更多信息
-		u, err := user.NewUser(c.Oauth.Context).GetUserInfo(openID)
+		u, err := user.NewUser(c.Oauth.Context).GetUserInfoContext(c.Request.Context(), openID)
 		if err != nil {
 			return err
 		}
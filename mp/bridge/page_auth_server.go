@@ -0,0 +1,45 @@
+package bridge
+
+import (
+	"net/http"
+
+	"github.com/MrCHI/gowechat/mp/user"
+	"github.com/MrCHI/gowechat/wxcontext"
+)
+
+//PageOAuthServer 微信网页授权的 http.Handler，启动时创建一次，可以并发处理请求
+type PageOAuthServer struct {
+	context                  *wxcontext.Context
+	myURLOfPageOAuthCallback string
+
+	//CheckOpenIDExisting 同 PageOAuthHandler.SetFuncCheckOpenIDExisting，可以通过w设置cookie、session等；为空时认为不存在
+	CheckOpenIDExisting func(w http.ResponseWriter, r *http.Request, openID string) (existing bool, stopNow bool)
+	//AfterGetUserInfo 同 PageOAuthHandler.SetFuncAfterGetUserInfo；为空时直接跳转到需要授权的页面
+	AfterGetUserInfo func(w http.ResponseWriter, r *http.Request, user user.Info) (stopNow bool)
+}
+
+//NewPageOAuthServer 实例化，myURLOfPageOAuthCallback 为该Handler对应的完整地址
+func NewPageOAuthServer(context *wxcontext.Context, myURLOfPageOAuthCallback string) *PageOAuthServer {
+	return &PageOAuthServer{context: context, myURLOfPageOAuthCallback: myURLOfPageOAuthCallback}
+}
+
+//ServeHTTP 实现http.Handler，失败时输出Warn日志并返回502
+func (s *PageOAuthServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h := NewPageOAuthHandler(s.context.WithRequest(r, w), s.myURLOfPageOAuthCallback)
+	h.SetFuncCheckOpenIDExisting(func(openID string) (bool, bool) {
+		if s.CheckOpenIDExisting == nil {
+			return false, false
+		}
+		return s.CheckOpenIDExisting(w, r, openID)
+	})
+	h.SetFuncAfterGetUserInfo(func(info user.Info) bool {
+		if s.AfterGetUserInfo == nil {
+			return false
+		}
+		return s.AfterGetUserInfo(w, r, info)
+	})
+	if err := h.Handle(); err != nil {
+		s.context.Log(r.Context(), wxcontext.LevelWarn, "网页授权失败", "error", err)
+		http.Error(w, "微信网页授权失败", http.StatusBadGateway)
+	}
+}
@@ -0,0 +1,132 @@
+package custom
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/MrCHI/gowechat/mp/base"
+	"github.com/MrCHI/gowechat/mp/message"
+	"github.com/MrCHI/gowechat/wxcontext"
+)
+
+const customSendURL = "https://api.weixin.qq.com/cgi-bin/message/custom/send"
+
+//Custom 客服消息，用户48小时内与公众号有过互动时可以发送
+type Custom struct {
+	base.MpBase
+}
+
+//NewCustom 实例化
+func NewCustom(context *wxcontext.Context) *Custom {
+	custom := new(Custom)
+	custom.Context = context
+	return custom
+}
+
+//Message 客服消息，按MsgType填写对应的字段
+type Message struct {
+	ToUser  string          `json:"touser"`
+	MsgType message.MsgType `json:"msgtype"`
+
+	Text  *Text  `json:"text,omitempty"`
+	Image *Media `json:"image,omitempty"`
+	Voice *Media `json:"voice,omitempty"`
+	Video *Video `json:"video,omitempty"`
+	Music *Music `json:"music,omitempty"`
+	News  *News  `json:"news,omitempty"`
+}
+
+//Text 文本消息
+type Text struct {
+	Content string `json:"content"`
+}
+
+//Media 图片、语音消息的素材
+type Media struct {
+	MediaID string `json:"media_id"`
+}
+
+//Video 视频消息
+type Video struct {
+	MediaID      string `json:"media_id"`
+	ThumbMediaID string `json:"thumb_media_id,omitempty"`
+	Title        string `json:"title,omitempty"`
+	Description  string `json:"description,omitempty"`
+}
+
+//Music 音乐消息
+type Music struct {
+	Title        string `json:"title,omitempty"`
+	Description  string `json:"description,omitempty"`
+	MusicURL     string `json:"musicurl"`
+	HQMusicURL   string `json:"hqmusicurl"`
+	ThumbMediaID string `json:"thumb_media_id"`
+}
+
+//News 图文消息（点击跳转到外链）
+type News struct {
+	Articles []*Article `json:"articles"`
+}
+
+//Article 图文消息中的文章
+type Article struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	URL         string `json:"url"`
+	PicURL      string `json:"picurl"`
+}
+
+//NewText 文本客服消息
+func NewText(toUser, content string) *Message {
+	return &Message{ToUser: toUser, MsgType: message.MsgTypeText, Text: &Text{content}}
+}
+
+//FromReply 把被动回复转换为客服消息，转发客服（MsgTypeTransfer）不能转换
+func FromReply(toUser string, reply *message.Reply) (*Message, error) {
+	msg := &Message{ToUser: toUser, MsgType: reply.MsgType}
+	switch data := reply.MsgData.(type) {
+	case *message.Text:
+		msg.Text = &Text{data.Content}
+	case *message.Image:
+		msg.Image = &Media{data.Image.MediaID}
+	case *message.Voice:
+		msg.Voice = &Media{data.Voice.MediaID}
+	case *message.Video:
+		msg.Video = &Video{MediaID: data.Video.MediaID, Title: data.Video.Title, Description: data.Video.Description}
+	case *message.Music:
+		msg.Music = &Music{data.Music.Title, data.Music.Description, data.Music.MusicURL, data.Music.HQMusicURL, data.Music.ThumbMediaID}
+	case *message.News:
+		msg.News = new(News)
+		for _, a := range data.Articles {
+			msg.News.Articles = append(msg.News.Articles, &Article{a.Title, a.Description, a.URL, a.PicURL})
+		}
+	default:
+		return nil, fmt.Errorf("%w: %s不能作为客服消息发送", message.ErrUnsupportReply, reply.MsgType)
+	}
+	return msg, nil
+}
+
+//Send 发送客服消息
+func (custom *Custom) Send(msg *Message) error {
+	return custom.SendContext(context.Background(), msg)
+}
+
+//SendContext 同 Send，支持 context
+func (custom *Custom) SendContext(ctx context.Context, msg *Message) error {
+	_, err := custom.HTTPPostJSONWithAccessTokenContext(ctx, customSendURL, msg)
+	return err
+}
+
+//SendReply 把被动回复作为客服消息发给toUser
+func (custom *Custom) SendReply(toUser string, reply *message.Reply) error {
+	return custom.SendReplyContext(context.Background(), toUser, reply)
+}
+
+//SendReplyContext 同 SendReply，支持 context
+func (custom *Custom) SendReplyContext(ctx context.Context, toUser string, reply *message.Reply) error {
+	msg, err := FromReply(toUser, reply)
+	if err != nil {
+		return err
+	}
+	return custom.SendContext(ctx, msg)
+}
@@ -1,6 +1,7 @@
 package jssdk
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"time"
@@ -52,9 +53,14 @@ func NewJs(context *wxcontext.Context) *Js {
 //GetConfig 获取jssdk需要的配置参数
 //uri 为当前网页地址
 func (js *Js) GetConfig(url string) (config *Config, err error) {
+	return js.GetConfigContext(context.Background(), url)
+}
+
+//GetConfigContext 同 GetConfig，支持 context
+func (js *Js) GetConfigContext(ctx context.Context, url string) (config *Config, err error) {
 	config = new(Config)
 	var ticketStr string
-	ticketStr, err = js.GetTicket()
+	ticketStr, err = js.GetTicketContext(ctx)
 	if err != nil {
 		return
 	}
@@ -73,47 +79,68 @@ func (js *Js) GetConfig(url string) (config *Config, err error) {
 
 //GetTicket 获取jsapi_tocket
 func (js *Js) GetTicket() (ticketStr string, err error) {
-	js.GetJsAPITicketLock().Lock()
-	defer js.GetJsAPITicketLock().Unlock()
+	return js.GetTicketContext(context.Background())
+}
 
-	//先从cache中取
-	jsAPITicketCacheKey := fmt.Sprintf("jsapi_ticket_%s", js.AppID)
-	val := js.Cache.Get(jsAPITicketCacheKey)
-	if val != nil {
-		ticketStr = val.(string)
+//GetTicketContext 同 GetTicket，支持 context
+func (js *Js) GetTicketContext(ctx context.Context) (ticketStr string, err error) {
+	if err = js.GetJsAPITicketLocker().Lock(ctx); err != nil {
 		return
 	}
-	var ticket resTicket
-	ticket, err = js.getTicketFromServer()
+	defer js.GetJsAPITicketLocker().Unlock()
+
+	//先从cache中取，多个进程共用缓存时只有一个去刷新
+	jsAPITicketCacheKey := fmt.Sprintf("jsapi_ticket_%s", js.AppID)
+	return js.RefreshToken(ctx, jsAPITicketCacheKey, js.fetchTicket)
+}
+
+//RefreshTask 供 wxcontext.Refresher 使用，提前刷新jsapi_ticket
+func (js *Js) RefreshTask() wxcontext.RefreshTask {
+	return wxcontext.RefreshTask{Name: "jsapi_ticket", Refresh: func(ctx context.Context) (next time.Duration, err error) {
+		if err = js.GetJsAPITicketLocker().Lock(ctx); err != nil {
+			return
+		}
+		defer js.GetJsAPITicketLocker().Unlock()
+
+		jsAPITicketCacheKey := fmt.Sprintf("jsapi_ticket_%s", js.AppID)
+		return js.RenewToken(ctx, jsAPITicketCacheKey, js.fetchTicket)
+	}}
+}
+
+//fetchTicket 实现wxcontext.FetchTokenFunc
+func (js *Js) fetchTicket(ctx context.Context) (string, time.Duration, error) {
+	ticket, err := js.getTicketFromServer(ctx)
 	if err != nil {
-		return
+		return "", 0, err
 	}
-	ticketStr = ticket.Ticket
-	return
+	return ticket.Ticket, time.Duration(ticket.ExpiresIn-1500) * time.Second, nil
 }
 
-//getTicketFromServer 强制从服务器中获取ticket
-func (js *Js) getTicketFromServer() (ticket resTicket, err error) {
+//getTicketFromServer 从服务器中获取ticket，不写缓存
+func (js *Js) getTicketFromServer(ctx context.Context) (ticket resTicket, err error) {
 	var accessToken string
-	accessToken, err = js.GetAccessToken()
+	accessToken, err = js.GetAccessTokenContext(ctx)
 	if err != nil {
 		return
 	}
 
 	var response []byte
-	url := fmt.Sprintf(getTicketURL, accessToken)
-	response, err = util.HTTPGet(url)
+	url := js.ResolveURL(fmt.Sprintf(getTicketURL, accessToken))
+	response, err = js.HTTPGetContext(ctx, url)
+	if err != nil {
+		return
+	}
 	err = json.Unmarshal(response, &ticket)
 	if err != nil {
 		return
 	}
 	if ticket.ErrCode != 0 {
-		err = fmt.Errorf("getTicket Error : errcode=%d , errmsg=%s", ticket.ErrCode, ticket.ErrMsg)
+		err = util.NewAPIError(getTicketURL, ticket.ErrCode, ticket.ErrMsg)
+		js.CountCall(ctx, getTicketURL, err)
+		js.Log(ctx, wxcontext.LevelError, "获取jsapi_ticket失败", "error", err)
 		return
 	}
-
-	jsAPITicketCacheKey := fmt.Sprintf("jsapi_ticket_%s", js.AppID)
-	expires := ticket.ExpiresIn - 1500
-	err = js.Cache.Put(jsAPITicketCacheKey, ticket.Ticket, time.Duration(expires)*time.Second)
+	js.CountCall(ctx, getTicketURL, nil)
+	js.Log(ctx, wxcontext.LevelInfo, "获取jsapi_ticket", "expires_in", ticket.ExpiresIn)
 	return
 }
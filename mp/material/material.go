@@ -1,9 +1,11 @@
 package material
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 
 	"github.com/MrCHI/gowechat/mp/base"
 	"github.com/MrCHI/gowechat/util"
@@ -77,20 +79,13 @@ type resArticles struct {
 
 //AddNews 新增永久图文素材
 func (material *Material) AddNews(articles []*Article) (mediaID string, err error) {
-	req := &reqArticles{articles}
-
-	responseBytes, err := material.HTTPPostJSONWithAccessToken(addNewsURL, req)
-	if err != nil {
-		return
-	}
+	return material.AddNewsContext(context.Background(), articles)
+}
 
-	var res resArticles
-	err = json.Unmarshal(responseBytes, res)
-	if err != nil {
-		return
-	}
-	mediaID = res.MediaID
-	return
+//AddNewsContext 同 AddNews，支持 context
+func (material *Material) AddNewsContext(ctx context.Context, articles []*Article) (mediaID string, err error) {
+	res, err := base.CallContext[*reqArticles, resArticles](ctx, &material.MpBase, addNewsURL, &reqArticles{articles})
+	return res.MediaID, err
 }
 
 //resAddMaterial 永久性素材上传返回的结果
@@ -103,33 +98,31 @@ type resAddMaterial struct {
 
 //AddMaterial 上传永久性素材（处理视频需要单独上传）
 func (material *Material) AddMaterial(mediaType MediaType, filename string) (mediaID string, url string, err error) {
+	return material.AddMaterialContext(context.Background(), mediaType, filename)
+}
+
+//AddMaterialContext 同 AddMaterial，支持 context
+func (material *Material) AddMaterialContext(ctx context.Context, mediaType MediaType, filename string) (mediaID string, url string, err error) {
+	return material.addMaterial(ctx, mediaType, util.MultipartFormField{IsFile: true, Fieldname: "media", Filename: filename})
+}
+
+//AddMaterialReader 从Reader上传永久性素材（视频使用 AddVideoReader），contentType为空时按filename的扩展名判断
+func (material *Material) AddMaterialReader(mediaType MediaType, filename, contentType string, r io.Reader) (mediaID string, url string, err error) {
+	return material.AddMaterialReaderContext(context.Background(), mediaType, filename, contentType, r)
+}
+
+//AddMaterialReaderContext 同 AddMaterialReader，支持 context
+func (material *Material) AddMaterialReaderContext(ctx context.Context, mediaType MediaType, filename, contentType string, r io.Reader) (mediaID string, url string, err error) {
+	return material.addMaterial(ctx, mediaType, readerField("media", filename, contentType, r))
+}
+
+func (material *Material) addMaterial(ctx context.Context, mediaType MediaType, file util.MultipartFormField) (mediaID string, url string, err error) {
 	if mediaType == MediaTypeVideo {
 		err = errors.New("永久视频素材上传使用 AddVideo 方法")
-	}
-	var accessToken string
-	accessToken, err = material.GetAccessToken()
-	if err != nil {
 		return
 	}
-
-	uri := fmt.Sprintf("%s?access_token=%s&type=%s", addMaterialURL, accessToken, mediaType)
-	var response []byte
-	response, err = util.PostFile("media", filename, uri)
-	if err != nil {
-		return
-	}
-	var resMaterial resAddMaterial
-	err = json.Unmarshal(response, &resMaterial)
-	if err != nil {
-		return
-	}
-	if resMaterial.ErrCode != 0 {
-		err = fmt.Errorf("AddMaterial error : errcode=%v , errmsg=%v", resMaterial.ErrCode, resMaterial.ErrMsg)
-		return
-	}
-	mediaID = resMaterial.MediaID
-	url = resMaterial.URL
-	return
+	uri := fmt.Sprintf("%s?type=%s", addMaterialURL, mediaType)
+	return material.postMaterial(ctx, uri, []util.MultipartFormField{file})
 }
 
 type reqVideo struct {
@@ -139,14 +132,25 @@ type reqVideo struct {
 
 //AddVideo 永久视频素材文件上传
 func (material *Material) AddVideo(filename, title, introduction string) (mediaID string, url string, err error) {
-	var accessToken string
-	accessToken, err = material.GetAccessToken()
-	if err != nil {
-		return
-	}
+	return material.AddVideoContext(context.Background(), filename, title, introduction)
+}
+
+//AddVideoContext 同 AddVideo，支持 context
+func (material *Material) AddVideoContext(ctx context.Context, filename, title, introduction string) (mediaID string, url string, err error) {
+	return material.addVideo(ctx, util.MultipartFormField{IsFile: true, Fieldname: "video", Filename: filename}, title, introduction)
+}
 
-	uri := fmt.Sprintf("%s?access_token=%s&type=video", addMaterialURL, accessToken)
+//AddVideoReader 从Reader上传永久视频素材，contentType为空时按filename的扩展名判断
+func (material *Material) AddVideoReader(filename, contentType string, r io.Reader, title, introduction string) (mediaID string, url string, err error) {
+	return material.AddVideoReaderContext(context.Background(), filename, contentType, r, title, introduction)
+}
+
+//AddVideoReaderContext 同 AddVideoReader，支持 context
+func (material *Material) AddVideoReaderContext(ctx context.Context, filename, contentType string, r io.Reader, title, introduction string) (mediaID string, url string, err error) {
+	return material.addVideo(ctx, readerField("video", filename, contentType, r), title, introduction)
+}
 
+func (material *Material) addVideo(ctx context.Context, file util.MultipartFormField, title, introduction string) (mediaID string, url string, err error) {
 	videoDesc := &reqVideo{
 		Title:        title,
 		Introduction: introduction,
@@ -158,33 +162,28 @@ func (material *Material) AddVideo(filename, title, introduction string) (mediaI
 	}
 
 	fields := []util.MultipartFormField{
+		file,
 		{
-			IsFile:    true,
-			Fieldname: "video",
-			Filename:  filename,
-		},
-		{
-			IsFile:    true,
 			Fieldname: "description",
 			Value:     fieldValue,
 		},
 	}
+	uri := fmt.Sprintf("%s?type=%s", addMaterialURL, MediaTypeVideo)
+	return material.postMaterial(ctx, uri, fields)
+}
 
+//postMaterial 上传永久素材
+func (material *Material) postMaterial(ctx context.Context, uri string, fields []util.MultipartFormField) (mediaID string, url string, err error) {
 	var response []byte
-	response, err = util.PostMultipartForm(fields, uri)
+	response, err = material.HTTPPostMultipartWithAccessTokenContext(ctx, uri, fields)
 	if err != nil {
 		return
 	}
-
 	var resMaterial resAddMaterial
 	err = json.Unmarshal(response, &resMaterial)
 	if err != nil {
 		return
 	}
-	if resMaterial.ErrCode != 0 {
-		err = fmt.Errorf("AddMaterial error : errcode=%v , errmsg=%v", resMaterial.ErrCode, resMaterial.ErrMsg)
-		return
-	}
 	mediaID = resMaterial.MediaID
 	url = resMaterial.URL
 	return
@@ -196,7 +195,12 @@ type reqDeleteMaterial struct {
 
 //DeleteMaterial 删除永久素材
 func (material *Material) DeleteMaterial(mediaID string) error {
-	_, err := material.HTTPPostJSONWithAccessToken(delMaterialURL, reqDeleteMaterial{mediaID})
+	return material.DeleteMaterialContext(context.Background(), mediaID)
+}
+
+//DeleteMaterialContext 同 DeleteMaterial，支持 context
+func (material *Material) DeleteMaterialContext(ctx context.Context, mediaID string) error {
+	_, err := material.HTTPPostJSONWithAccessTokenContext(ctx, delMaterialURL, reqDeleteMaterial{mediaID})
 	if err != nil {
 		return err
 	}
@@ -211,6 +215,11 @@ type reqBatchGat struct {
 
 // 获取素材列表
 func (material *Material) BatchGet(materialType string, offset, count int) ([]byte, error) {
+	return material.BatchGetContext(context.Background(), materialType, offset, count)
+}
+
+//BatchGetContext 同 BatchGet，支持 context
+func (material *Material) BatchGetContext(ctx context.Context, materialType string, offset, count int) ([]byte, error) {
 	if offset < 0 {
 		offset = 0
 	}
@@ -225,7 +234,7 @@ func (material *Material) BatchGet(materialType string, offset, count int) ([]by
 		Count:        count,
 	}
 
-	result, err := material.HTTPPostJSONWithAccessToken(batchgetMaterialURL, request)
+	result, err := material.HTTPPostJSONWithAccessTokenContext(ctx, batchgetMaterialURL, request)
 
 	if err != nil {
 		return nil, err
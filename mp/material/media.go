@@ -1,8 +1,10 @@
 package material
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 
 	"github.com/MrCHI/gowechat/util"
 )
@@ -39,38 +41,50 @@ type Media struct {
 
 //MediaUpload 临时素材上传
 func (material *Material) MediaUpload(mediaType MediaType, filename string) (media Media, err error) {
-	var accessToken string
-	accessToken, err = material.GetAccessToken()
-	if err != nil {
-		return
-	}
+	return material.MediaUploadContext(context.Background(), mediaType, filename)
+}
+
+//MediaUploadContext 同 MediaUpload，支持 context
+func (material *Material) MediaUploadContext(ctx context.Context, mediaType MediaType, filename string) (media Media, err error) {
+	return material.mediaUpload(ctx, mediaType, util.MultipartFormField{IsFile: true, Fieldname: "media", Filename: filename})
+}
+
+//MediaUploadReader 从Reader上传临时素材，不需要先写入临时文件。
+//filename用于微信判断文件格式，contentType为空时按filename的扩展名判断
+func (material *Material) MediaUploadReader(mediaType MediaType, filename, contentType string, r io.Reader) (media Media, err error) {
+	return material.MediaUploadReaderContext(context.Background(), mediaType, filename, contentType, r)
+}
 
-	uri := fmt.Sprintf("%s?access_token=%s&type=%s", mediaUploadURL, accessToken, mediaType)
+//MediaUploadReaderContext 同 MediaUploadReader，支持 context
+func (material *Material) MediaUploadReaderContext(ctx context.Context, mediaType MediaType, filename, contentType string, r io.Reader) (media Media, err error) {
+	return material.mediaUpload(ctx, mediaType, readerField("media", filename, contentType, r))
+}
+
+func (material *Material) mediaUpload(ctx context.Context, mediaType MediaType, file util.MultipartFormField) (media Media, err error) {
+	uri := fmt.Sprintf("%s?type=%s", mediaUploadURL, mediaType)
 	var response []byte
-	response, err = util.PostFile("media", filename, uri)
+	response, err = material.HTTPPostMultipartWithAccessTokenContext(ctx, uri, []util.MultipartFormField{file})
 	if err != nil {
 		return
 	}
 	err = json.Unmarshal(response, &media)
-	if err != nil {
-		return
-	}
-	if media.ErrCode != 0 {
-		err = fmt.Errorf("MediaUpload error : errcode=%v , errmsg=%v", media.ErrCode, media.ErrMsg)
-		return
-	}
 	return
 }
 
 //GetMediaURL 返回临时素材的下载地址供用户自己处理
 //NOTICE: URL 不可公开，因为含access_token 需要立即另存文件
 func (material *Material) GetMediaURL(mediaID string) (mediaURL string, err error) {
+	return material.GetMediaURLContext(context.Background(), mediaID)
+}
+
+//GetMediaURLContext 同 GetMediaURL，支持 context
+func (material *Material) GetMediaURLContext(ctx context.Context, mediaID string) (mediaURL string, err error) {
 	var accessToken string
-	accessToken, err = material.GetAccessToken()
+	accessToken, err = material.GetAccessTokenContext(ctx)
 	if err != nil {
 		return
 	}
-	mediaURL = fmt.Sprintf("%s?access_token=%s&media_id=%s", mediaGetURL, accessToken, mediaID)
+	mediaURL = fmt.Sprintf("%s?access_token=%s&media_id=%s", material.ResolveURL(mediaGetURL), accessToken, mediaID)
 	return
 }
 
@@ -83,15 +97,27 @@ type resMediaImage struct {
 
 //ImageUpload 图片上传
 func (material *Material) ImageUpload(filename string) (url string, err error) {
-	var accessToken string
-	accessToken, err = material.GetAccessToken()
-	if err != nil {
-		return
-	}
+	return material.ImageUploadContext(context.Background(), filename)
+}
+
+//ImageUploadContext 同 ImageUpload，支持 context
+func (material *Material) ImageUploadContext(ctx context.Context, filename string) (url string, err error) {
+	return material.imageUpload(ctx, util.MultipartFormField{IsFile: true, Fieldname: "media", Filename: filename})
+}
 
-	uri := fmt.Sprintf("%s?access_token=%s", mediaUploadImageURL, accessToken)
+//ImageUploadReader 从Reader上传图文消息内的图片，contentType为空时按filename的扩展名判断
+func (material *Material) ImageUploadReader(filename, contentType string, r io.Reader) (url string, err error) {
+	return material.ImageUploadReaderContext(context.Background(), filename, contentType, r)
+}
+
+//ImageUploadReaderContext 同 ImageUploadReader，支持 context
+func (material *Material) ImageUploadReaderContext(ctx context.Context, filename, contentType string, r io.Reader) (url string, err error) {
+	return material.imageUpload(ctx, readerField("media", filename, contentType, r))
+}
+
+func (material *Material) imageUpload(ctx context.Context, file util.MultipartFormField) (url string, err error) {
 	var response []byte
-	response, err = util.PostFile("media", filename, uri)
+	response, err = material.HTTPPostMultipartWithAccessTokenContext(ctx, mediaUploadImageURL, []util.MultipartFormField{file})
 	if err != nil {
 		return
 	}
@@ -100,11 +126,17 @@ func (material *Material) ImageUpload(filename string) (url string, err error) {
 	if err != nil {
 		return
 	}
-	if image.ErrCode != 0 {
-		err = fmt.Errorf("UploadImage error : errcode=%v , errmsg=%v", image.ErrCode, image.ErrMsg)
-		return
-	}
 	url = image.URL
 	return
+}
 
+//readerField 从Reader上传的文件字段
+func readerField(fieldname, filename, contentType string, r io.Reader) util.MultipartFormField {
+	return util.MultipartFormField{
+		IsFile:      true,
+		Fieldname:   fieldname,
+		Filename:    filename,
+		ContentType: contentType,
+		Reader:      r,
+	}
 }
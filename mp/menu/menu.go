@@ -1,7 +1,7 @@
 package menu
 
 import (
-	"encoding/json"
+	"context"
 
 	"github.com/MrCHI/gowechat/mp/base"
 	"github.com/MrCHI/gowechat/util"
@@ -120,10 +120,15 @@ func NewMenu(context *wxcontext.Context) *Menu {
 
 //SetMenu 设置按钮
 func (menu *Menu) SetMenu(buttons []*Button) error {
+	return menu.SetMenuContext(context.Background(), buttons)
+}
+
+//SetMenuContext 同 SetMenu，支持 context
+func (menu *Menu) SetMenuContext(ctx context.Context, buttons []*Button) error {
 	reqMenu := &reqMenu{
 		Button: buttons,
 	}
-	_, err := menu.HTTPPostJSONWithAccessToken(menuCreateURL, reqMenu)
+	_, err := menu.HTTPPostJSONWithAccessTokenContext(ctx, menuCreateURL, reqMenu)
 	if err != nil {
 		return err
 	}
@@ -132,28 +137,37 @@ func (menu *Menu) SetMenu(buttons []*Button) error {
 
 //GetMenu 获取菜单配置
 func (menu *Menu) GetMenu() (resMenu ResMenu, err error) {
-	var response []byte
-	response, err = menu.HTTPGetWithAccessToken(menuGetURL)
-	if err != nil {
-		return
-	}
-	err = json.Unmarshal(response, &resMenu)
-	return
+	return menu.GetMenuContext(context.Background())
+}
+
+//GetMenuContext 同 GetMenu，支持 context
+func (menu *Menu) GetMenuContext(ctx context.Context) (resMenu ResMenu, err error) {
+	return base.GetContext[ResMenu](ctx, &menu.MpBase, menuGetURL)
 }
 
 //DeleteMenu 删除菜单
 func (menu *Menu) DeleteMenu() (err error) {
-	_, err = menu.HTTPGetWithAccessToken(menuDeleteURL)
+	return menu.DeleteMenuContext(context.Background())
+}
+
+//DeleteMenuContext 同 DeleteMenu，支持 context
+func (menu *Menu) DeleteMenuContext(ctx context.Context) (err error) {
+	_, err = menu.HTTPGetWithAccessTokenContext(ctx, menuDeleteURL)
 	return
 }
 
 //AddConditional 添加个性化菜单
 func (menu *Menu) AddConditional(buttons []*Button, matchRule *MatchRule) error {
+	return menu.AddConditionalContext(context.Background(), buttons, matchRule)
+}
+
+//AddConditionalContext 同 AddConditional，支持 context
+func (menu *Menu) AddConditionalContext(ctx context.Context, buttons []*Button, matchRule *MatchRule) error {
 	reqMenu := &reqMenu{
 		Button:    buttons,
 		MatchRule: matchRule,
 	}
-	_, err := menu.HTTPPostJSONWithAccessToken(menuAddConditionalURL, reqMenu)
+	_, err := menu.HTTPPostJSONWithAccessTokenContext(ctx, menuAddConditionalURL, reqMenu)
 	if err != nil {
 		return err
 	}
@@ -162,10 +176,15 @@ func (menu *Menu) AddConditional(buttons []*Button, matchRule *MatchRule) error
 
 //DeleteConditional 删除个性化菜单
 func (menu *Menu) DeleteConditional(menuID int64) error {
+	return menu.DeleteConditionalContext(context.Background(), menuID)
+}
+
+//DeleteConditionalContext 同 DeleteConditional，支持 context
+func (menu *Menu) DeleteConditionalContext(ctx context.Context, menuID int64) error {
 	reqDeleteConditional := &reqDeleteConditional{
 		MenuID: menuID,
 	}
-	_, err := menu.HTTPPostJSONWithAccessToken(menuDeleteConditionalURL, reqDeleteConditional)
+	_, err := menu.HTTPPostJSONWithAccessTokenContext(ctx, menuDeleteConditionalURL, reqDeleteConditional)
 	if err != nil {
 		return err
 	}
@@ -174,28 +193,21 @@ func (menu *Menu) DeleteConditional(menuID int64) error {
 
 //MenuTryMatch 菜单匹配
 func (menu *Menu) MenuTryMatch(userID string) (buttons []Button, err error) {
-	reqMenuTryMatch := &reqMenuTryMatch{userID}
-	var response []byte
-	response, err = menu.HTTPPostJSONWithAccessToken(menuTryMatchURL, reqMenuTryMatch)
-	if err != nil {
-		return
-	}
-	var resMenuTryMatch resMenuTryMatch
-	err = json.Unmarshal(response, &resMenuTryMatch)
-	if err != nil {
-		return
-	}
-	buttons = resMenuTryMatch.Button
-	return
+	return menu.MenuTryMatchContext(context.Background(), userID)
+}
+
+//MenuTryMatchContext 同 MenuTryMatch，支持 context
+func (menu *Menu) MenuTryMatchContext(ctx context.Context, userID string) (buttons []Button, err error) {
+	res, err := base.CallContext[*reqMenuTryMatch, resMenuTryMatch](ctx, &menu.MpBase, menuTryMatchURL, &reqMenuTryMatch{userID})
+	return res.Button, err
 }
 
 //GetCurrentSelfMenuInfo 获取自定义菜单配置接口
 func (menu *Menu) GetCurrentSelfMenuInfo() (resSelfMenuInfo ResSelfMenuInfo, err error) {
-	var response []byte
-	response, err = menu.HTTPGetWithAccessToken(menuSelfMenuInfoURL)
-	if err != nil {
-		return
-	}
-	err = json.Unmarshal(response, &resSelfMenuInfo)
-	return
+	return menu.GetCurrentSelfMenuInfoContext(context.Background())
+}
+
+//GetCurrentSelfMenuInfoContext 同 GetCurrentSelfMenuInfo，支持 context
+func (menu *Menu) GetCurrentSelfMenuInfoContext(ctx context.Context) (resSelfMenuInfo ResSelfMenuInfo, err error) {
+	return base.GetContext[ResSelfMenuInfo](ctx, &menu.MpBase, menuSelfMenuInfoURL)
 }
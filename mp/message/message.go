@@ -60,6 +60,28 @@ const (
 	EventLocationSelect = "location_select"
 	// 消息事件推送，在模版消息发送任务完成后，微信服务器会将是否送达成功作为通知
 	EventTempLateSendJobFinish = "TEMPLATESENDJOBFINISH"
+	//EventViewMiniprogram 点击菜单跳转小程序的事件推送
+	EventViewMiniprogram = "view_miniprogram"
+	//EventMassSendJobFinish 群发任务完成后推送群发的结果
+	EventMassSendJobFinish = "MASSSENDJOBFINISH"
+	//EventSubscribeMsgPopup 用户操作订阅通知弹窗
+	EventSubscribeMsgPopup = "subscribe_msg_popup_event"
+	//EventSubscribeMsgChange 用户管理订阅通知
+	EventSubscribeMsgChange = "subscribe_msg_change_event"
+	//EventSubscribeMsgSent 发送订阅通知的结果
+	EventSubscribeMsgSent = "subscribe_msg_sent_event"
+	//EventCardPassCheck 卡券审核通过
+	EventCardPassCheck = "card_pass_check"
+	//EventCardNotPassCheck 卡券审核未通过
+	EventCardNotPassCheck = "card_not_pass_check"
+	//EventUserGetCard 用户领取卡券
+	EventUserGetCard = "user_get_card"
+	//EventUserDelCard 用户删除卡券
+	EventUserDelCard = "user_del_card"
+	//EventUserConsumeCard 卡券被核销
+	EventUserConsumeCard = "user_consume_card"
+	//EventUserPayFromPayCell 用户通过卡券的微信买单完成时
+	EventUserPayFromPayCell = "user_pay_from_pay_cell"
 )
 
 //MixMessage 存放所有微信发送过来的消息和事件
@@ -80,6 +102,7 @@ type MixMessage struct {
 	Title        string  `xml:"Title"`
 	Description  string  `xml:"Description"`
 	URL          string  `xml:"Url"`
+	Recognition  string  `xml:"Recognition"` //语音识别结果，开通语音识别后才有
 
 	//事件相关
 	Event     EventType `xml:"Event"`
@@ -90,23 +113,28 @@ type MixMessage struct {
 	Precision string    `xml:"Precision"`
 	MenuID    string    `xml:"MenuId"`
 
-	ScanCodeInfo struct {
-		ScanType   string `xml:"ScanType"`
-		ScanResult string `xml:"ScanResult"`
-	} `xml:"ScanCodeInfo"`
-
-	SendPicsInfo struct {
-		Count   int32      `xml:"Count"`
-		PicList []EventPic `xml:"PicList>item"`
-	} `xml:"SendPicsInfo"`
-
-	SendLocationInfo struct {
-		LocationX float64 `xml:"Location_X"`
-		LocationY float64 `xml:"Location_Y"`
-		Scale     float64 `xml:"Scale"`
-		Label     string  `xml:"Label"`
-		Poiname   string  `xml:"Poiname"`
-	}
+	ScanCodeInfo     ScanCodeInfo     `xml:"ScanCodeInfo"`
+	SendPicsInfo     SendPicsInfo     `xml:"SendPicsInfo"`
+	SendLocationInfo SendLocationInfo `xml:"SendLocationInfo"`
+
+	//模板消息、群发的结果，注意xml中为MsgID，与普通消息的MsgId不同
+	JobMsgID    int64  `xml:"MsgID"`
+	Status      string `xml:"Status"`
+	TotalCount  int    `xml:"TotalCount"`
+	FilterCount int    `xml:"FilterCount"`
+	SentCount   int    `xml:"SentCount"`
+	ErrorCount  int    `xml:"ErrorCount"`
+
+	//订阅通知
+	SubscribeMsgPopupEvent  []SubscribeMsgItem `xml:"SubscribeMsgPopupEvent>List"`
+	SubscribeMsgChangeEvent []SubscribeMsgItem `xml:"SubscribeMsgChangeEvent>List"`
+	SubscribeMsgSentEvent   []SubscribeMsgItem `xml:"SubscribeMsgSentEvent>List"`
+
+	//卡券、买单
+	CardInfo
+
+	//Raw 推送的明文xml，用于读取没有封装的字段
+	Raw []byte `xml:"-"`
 }
 
 //EventPic 发图事件推送
@@ -114,6 +142,56 @@ type EventPic struct {
 	PicMd5Sum string `xml:"PicMd5Sum"`
 }
 
+//ScanCodeInfo 扫码事件的扫描信息
+type ScanCodeInfo struct {
+	ScanType   string `xml:"ScanType"`
+	ScanResult string `xml:"ScanResult"`
+}
+
+//SendPicsInfo 发图事件的图片信息
+type SendPicsInfo struct {
+	Count   int32      `xml:"Count"`
+	PicList []EventPic `xml:"PicList>item"`
+}
+
+//SendLocationInfo 地理位置选择器事件的位置信息
+type SendLocationInfo struct {
+	LocationX float64 `xml:"Location_X"`
+	LocationY float64 `xml:"Location_Y"`
+	Scale     float64 `xml:"Scale"`
+	Label     string  `xml:"Label"`
+	Poiname   string  `xml:"Poiname"`
+}
+
+//SubscribeMsgItem 订阅通知事件中的一个模板，不同事件使用不同的字段
+type SubscribeMsgItem struct {
+	TemplateID            string `xml:"TemplateId"`
+	SubscribeStatusString string `xml:"SubscribeStatusString"` //accept、reject
+	PopupScene            int    `xml:"PopupScene"`            //弹窗场景，0：JSAPI，1：发送后，2：图文
+	MsgID                 string `xml:"MsgID"`
+	ErrorCode             int    `xml:"ErrorCode"`
+	ErrorStatus           string `xml:"ErrorStatus"`
+}
+
+//CardInfo 卡券和买单事件的字段
+type CardInfo struct {
+	CardID          string `xml:"CardId"`
+	UserCardCode    string `xml:"UserCardCode"`
+	RefuseReason    string `xml:"RefuseReason"`    //审核未通过的原因
+	IsGiveByFriend  int    `xml:"IsGiveByFriend"`  //是否为转赠领取，1为是
+	FriendUserName  string `xml:"FriendUserName"`  //转赠方的openid
+	OldUserCardCode string `xml:"OldUserCardCode"` //转赠前的code
+	OuterStr        string `xml:"OuterStr"`        //领取场景值
+	UnionID         string `xml:"UnionId"`
+	ConsumeSource   string `xml:"ConsumeSource"` //核销来源
+	LocationName    string `xml:"LocationName"`
+	StaffOpenID     string `xml:"StaffOpenId"`
+	TransID         string `xml:"TransId"` //买单的微信支付交易订单号
+	LocationID      int64  `xml:"LocationId"`
+	Fee             int    `xml:"Fee"`         //实付金额，单位分
+	OriginalFee     int    `xml:"OriginalFee"` //应付金额，单位分
+}
+
 //EncryptedXMLMsg 安全模式下的消息体
 type EncryptedXMLMsg struct {
 	XMLName      struct{} `xml:"xml" json:"-"`
@@ -0,0 +1,182 @@
+package message
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/MrCHI/gowechat/wxcontext"
+)
+
+//HandlerFunc 消息处理方法，返回nil时不回复
+type HandlerFunc func(ctx context.Context, msg *MixMessage) *Reply
+
+//Middleware 包裹在HandlerFunc外层，用于日志、鉴权、panic恢复等
+type Middleware func(next HandlerFunc) HandlerFunc
+
+//Router 按消息类型、事件、EventKey、关键字分发消息，
+//优先级为 事件+EventKey > 事件 > 关键字 > 消息类型 > Fallback。
+//需要在开始处理消息前注册完毕，之后可以并发使用
+type Router struct {
+	middlewares []Middleware
+	msgTypes    map[MsgType]HandlerFunc
+	events      map[EventType]HandlerFunc
+	eventKeys   map[eventKey]HandlerFunc
+	keywords    []keywordRoute
+	fallback    HandlerFunc
+}
+
+type eventKey struct {
+	event EventType
+	key   string
+}
+
+type keywordRoute struct {
+	pattern *regexp.Regexp
+	handler HandlerFunc
+}
+
+//NewRouter 实例化
+func NewRouter() *Router {
+	return &Router{
+		msgTypes:  make(map[MsgType]HandlerFunc),
+		events:    make(map[EventType]HandlerFunc),
+		eventKeys: make(map[eventKey]HandlerFunc),
+	}
+}
+
+//Use 添加中间件，先添加的在最外层
+func (r *Router) Use(middlewares ...Middleware) {
+	r.middlewares = append(r.middlewares, middlewares...)
+}
+
+//MsgType 处理某类普通消息，例如 MsgTypeImage
+func (r *Router) MsgType(msgType MsgType, handler HandlerFunc) {
+	r.msgTypes[msgType] = handler
+}
+
+//Text 处理文本消息，没有匹配到关键字时调用
+func (r *Router) Text(handler HandlerFunc) {
+	r.MsgType(MsgTypeText, handler)
+}
+
+//Image 处理图片消息
+func (r *Router) Image(handler HandlerFunc) {
+	r.MsgType(MsgTypeImage, handler)
+}
+
+//Voice 处理语音消息
+func (r *Router) Voice(handler HandlerFunc) {
+	r.MsgType(MsgTypeVoice, handler)
+}
+
+//Event 处理某个事件，例如 EventSubscribe、EventTempLateSendJobFinish
+func (r *Router) Event(event EventType, handler HandlerFunc) {
+	r.events[event] = handler
+}
+
+//EventKey 处理EventKey为key的事件，例如某个CLICK菜单
+func (r *Router) EventKey(event EventType, key string, handler HandlerFunc) {
+	r.eventKeys[eventKey{event, key}] = handler
+}
+
+//Click 处理点击菜单key的事件
+func (r *Router) Click(key string, handler HandlerFunc) {
+	r.EventKey(EventClick, key, handler)
+}
+
+//Keyword 处理内容（去掉首尾空白后）等于keyword的文本消息
+func (r *Router) Keyword(keyword string, handler HandlerFunc) {
+	r.KeywordRegexp(regexp.MustCompile("^"+regexp.QuoteMeta(keyword)+"$"), handler)
+}
+
+//KeywordRegexp 处理内容（去掉首尾空白后）匹配pattern的文本消息，按注册顺序匹配
+func (r *Router) KeywordRegexp(pattern *regexp.Regexp, handler HandlerFunc) {
+	r.keywords = append(r.keywords, keywordRoute{pattern, handler})
+}
+
+//Fallback 没有匹配到任何处理方法时调用，不设置时不回复
+func (r *Router) Fallback(handler HandlerFunc) {
+	r.fallback = handler
+}
+
+//Serve 分发消息，经过所有中间件
+func (r *Router) Serve(ctx context.Context, msg *MixMessage) *Reply {
+	handler := r.route(msg)
+	for i := len(r.middlewares) - 1; i >= 0; i-- {
+		handler = r.middlewares[i](handler)
+	}
+	return handler(ctx, msg)
+}
+
+//Handle 同 Serve，用于 MsgHandler.SetHandleMessageFunc
+func (r *Router) Handle(msg MixMessage) *Reply {
+	return r.Serve(context.Background(), &msg)
+}
+
+//route 找到处理方法，没有时返回不回复的方法
+func (r *Router) route(msg *MixMessage) HandlerFunc {
+	if msg.MsgType == MsgTypeEvent {
+		if h, ok := r.eventKeys[eventKey{msg.Event, msg.EventKey}]; ok {
+			return h
+		}
+		if h, ok := r.events[msg.Event]; ok {
+			return h
+		}
+	}
+	if msg.MsgType == MsgTypeText {
+		content := strings.TrimSpace(msg.Content)
+		for _, route := range r.keywords {
+			if route.pattern.MatchString(content) {
+				return route.handler
+			}
+		}
+	}
+	if h, ok := r.msgTypes[msg.MsgType]; ok {
+		return h
+	}
+	if r.fallback != nil {
+		return r.fallback
+	}
+	return func(context.Context, *MixMessage) *Reply { return nil }
+}
+
+//Recover 处理方法panic时输出Error日志并不回复，logger为空时只恢复
+func Recover(logger wxcontext.Logger) Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, msg *MixMessage) (reply *Reply) {
+			defer func() {
+				if e := recover(); e != nil {
+					reply = nil
+					if logger != nil {
+						logger.Log(ctx, wxcontext.LevelError, "处理消息panic",
+							"msg_type", msg.MsgType, "event", msg.Event,
+							"error", fmt.Sprint(e), "stack", string(debug.Stack()))
+					}
+				}
+			}()
+			return next(ctx, msg)
+		}
+	}
+}
+
+//Logging 每条消息输出一条Debug日志，包括消息类型、事件、回复类型和耗时
+func Logging(logger wxcontext.Logger) Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, msg *MixMessage) *Reply {
+			start := time.Now()
+			reply := next(ctx, msg)
+			var replyType MsgType
+			if reply != nil {
+				replyType = reply.MsgType
+			}
+			logger.Log(ctx, wxcontext.LevelDebug, "处理消息",
+				"msg_type", msg.MsgType, "event", msg.Event, "event_key", msg.EventKey,
+				"from", msg.FromUserName, "reply", replyType, "duration", time.Since(start))
+			return reply
+		}
+	}
+}
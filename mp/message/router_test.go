@@ -0,0 +1,79 @@
+package message
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func reply(name string) HandlerFunc {
+	return func(ctx context.Context, msg *MixMessage) *Reply {
+		return &Reply{MsgType: MsgTypeText, MsgData: NewText(name)}
+	}
+}
+
+func TestRouter(t *testing.T) {
+	r := NewRouter()
+	var trace []string
+	r.Use(func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, msg *MixMessage) *Reply {
+			trace = append(trace, "outer")
+			return next(ctx, msg)
+		}
+	}, func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, msg *MixMessage) *Reply {
+			trace = append(trace, "inner")
+			return next(ctx, msg)
+		}
+	})
+	r.Text(reply("text"))
+	r.Keyword("help", reply("help"))
+	r.KeywordRegexp(regexp.MustCompile(`^订单\d+$`), reply("order"))
+	r.Image(reply("image"))
+	r.Event(EventSubscribe, reply("subscribe"))
+	r.EventKey(EventSubscribe, "qrscene_1", reply("subscribe qrscene_1"))
+	r.Click("V1001", reply("click V1001"))
+	r.Event(EventTempLateSendJobFinish, reply("template"))
+	r.Fallback(reply("fallback"))
+
+	tests := []struct {
+		msg  MixMessage
+		want string
+	}{
+		{MixMessage{CommonToken: CommonToken{MsgType: MsgTypeText}, Content: " help "}, "help"},
+		{MixMessage{CommonToken: CommonToken{MsgType: MsgTypeText}, Content: "订单123"}, "order"},
+		{MixMessage{CommonToken: CommonToken{MsgType: MsgTypeText}, Content: "help me"}, "text"},
+		{MixMessage{CommonToken: CommonToken{MsgType: MsgTypeImage}}, "image"},
+		{MixMessage{CommonToken: CommonToken{MsgType: MsgTypeEvent}, Event: EventSubscribe}, "subscribe"},
+		{MixMessage{CommonToken: CommonToken{MsgType: MsgTypeEvent}, Event: EventSubscribe, EventKey: "qrscene_1"}, "subscribe qrscene_1"},
+		{MixMessage{CommonToken: CommonToken{MsgType: MsgTypeEvent}, Event: EventClick, EventKey: "V1001"}, "click V1001"},
+		{MixMessage{CommonToken: CommonToken{MsgType: MsgTypeEvent}, Event: EventClick, EventKey: "V1002"}, "fallback"},
+		{MixMessage{CommonToken: CommonToken{MsgType: MsgTypeEvent}, Event: EventTempLateSendJobFinish}, "template"},
+		{MixMessage{CommonToken: CommonToken{MsgType: MsgTypeVoice}}, "fallback"},
+	}
+	for _, tt := range tests {
+		trace = nil
+		res := r.Handle(tt.msg)
+		if got := res.MsgData.(*Text).Content; got != tt.want {
+			t.Errorf("%s/%s/%q: got %q, want %q", tt.msg.MsgType, tt.msg.Event, tt.msg.Content+tt.msg.EventKey, got, tt.want)
+		}
+		if strings.Join(trace, ",") != "outer,inner" {
+			t.Errorf("middleware order %v", trace)
+		}
+	}
+}
+
+func TestRecover(t *testing.T) {
+	r := NewRouter()
+	r.Use(Recover(nil))
+	r.Text(func(ctx context.Context, msg *MixMessage) *Reply {
+		panic("boom")
+	})
+	if res := r.Handle(MixMessage{CommonToken: CommonToken{MsgType: MsgTypeText}}); res != nil {
+		t.Errorf("want no reply after panic, got %+v", res)
+	}
+	if res := NewRouter().Handle(MixMessage{}); res != nil {
+		t.Errorf("want no reply without handlers, got %+v", res)
+	}
+}
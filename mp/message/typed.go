@@ -0,0 +1,277 @@
+package message
+
+import (
+	"encoding/xml"
+	"strconv"
+	"strings"
+)
+
+//Message 解析后的消息或事件，通过类型断言得到具体类型，例如 *TextMessage、*SubscribeEvent，
+//没有封装的消息和事件为 *UnknownMessage
+type Message interface {
+	Common() *CommonToken
+}
+
+//Common 消息的通用字段
+func (msg *CommonToken) Common() *CommonToken {
+	return msg
+}
+
+//Parse 解析推送的明文xml，返回具体类型的消息或事件
+func Parse(raw []byte) (Message, error) {
+	msg, err := ParseMixMessage(raw)
+	if err != nil {
+		return nil, err
+	}
+	return msg.Typed(), nil
+}
+
+//ParseMixMessage 解析推送的明文xml，保留在Raw中
+func ParseMixMessage(raw []byte) (MixMessage, error) {
+	var msg MixMessage
+	if err := xml.Unmarshal(raw, &msg); err != nil {
+		return MixMessage{}, err
+	}
+	msg.Raw = raw
+	return msg, nil
+}
+
+//TextMessage 文本消息
+type TextMessage struct {
+	CommonToken
+	MsgID   int64
+	Content string
+}
+
+//ImageMessage 图片消息
+type ImageMessage struct {
+	CommonToken
+	MsgID   int64
+	PicURL  string
+	MediaID string
+}
+
+//VoiceMessage 语音消息
+type VoiceMessage struct {
+	CommonToken
+	MsgID       int64
+	MediaID     string
+	Format      string
+	Recognition string //语音识别结果，开通语音识别后才有
+}
+
+//VideoMessage 视频、小视频消息，通过MsgType区分
+type VideoMessage struct {
+	CommonToken
+	MsgID        int64
+	MediaID      string
+	ThumbMediaID string
+}
+
+//LocationMessage 地理位置消息
+type LocationMessage struct {
+	CommonToken
+	MsgID     int64
+	LocationX float64
+	LocationY float64
+	Scale     float64
+	Label     string
+}
+
+//LinkMessage 链接消息
+type LinkMessage struct {
+	CommonToken
+	MsgID       int64
+	Title       string
+	Description string
+	URL         string
+}
+
+//SubscribeEvent 关注事件，扫描带参数二维码关注时Scene为场景值（去掉qrscene_前缀）
+type SubscribeEvent struct {
+	CommonToken
+	EventKey string
+	Ticket   string
+	Scene    string
+}
+
+//UnsubscribeEvent 取消关注事件
+type UnsubscribeEvent struct {
+	CommonToken
+}
+
+//ScanEvent 已关注的用户扫描带参数二维码
+type ScanEvent struct {
+	CommonToken
+	Scene  string
+	Ticket string
+}
+
+//LocationEvent 上报地理位置事件
+type LocationEvent struct {
+	CommonToken
+	Latitude  float64
+	Longitude float64
+	Precision float64
+}
+
+//ClickEvent 点击菜单拉取消息
+type ClickEvent struct {
+	CommonToken
+	EventKey string
+}
+
+//ViewEvent 点击菜单跳转链接
+type ViewEvent struct {
+	CommonToken
+	URL    string
+	MenuID string
+}
+
+//ViewMiniprogramEvent 点击菜单跳转小程序
+type ViewMiniprogramEvent struct {
+	CommonToken
+	PagePath string
+	MenuID   string
+}
+
+//ScanCodeEvent 扫码推事件，Event为 EventScancodePush 或 EventScancodeWaitmsg
+type ScanCodeEvent struct {
+	CommonToken
+	Event        EventType
+	EventKey     string
+	ScanCodeInfo ScanCodeInfo
+}
+
+//PicEvent 发图事件，Event为 EventPicSysphoto、EventPicPhotoOrAlbum 或 EventPicWeixin
+type PicEvent struct {
+	CommonToken
+	Event        EventType
+	EventKey     string
+	SendPicsInfo SendPicsInfo
+}
+
+//LocationSelectEvent 地理位置选择器事件
+type LocationSelectEvent struct {
+	CommonToken
+	EventKey         string
+	SendLocationInfo SendLocationInfo
+}
+
+//TemplateSendJobFinishEvent 模板消息发送结果，Status为 success、failed:user block 或 failed: system failed
+type TemplateSendJobFinishEvent struct {
+	CommonToken
+	MsgID  int64
+	Status string
+}
+
+//Success 是否送达成功
+func (event *TemplateSendJobFinishEvent) Success() bool {
+	return event.Status == "success"
+}
+
+//MassSendJobFinishEvent 群发结果
+type MassSendJobFinishEvent struct {
+	CommonToken
+	MsgID       int64
+	Status      string
+	TotalCount  int //粉丝数
+	FilterCount int //过滤后准备发送的粉丝数
+	SentCount   int //发送成功的粉丝数
+	ErrorCount  int //发送失败的粉丝数
+}
+
+//SubscribeMsgEvent 订阅通知事件，Event为 EventSubscribeMsgPopup、EventSubscribeMsgChange 或 EventSubscribeMsgSent
+type SubscribeMsgEvent struct {
+	CommonToken
+	Event EventType
+	List  []SubscribeMsgItem
+}
+
+//CardEvent 卡券审核、领取、删除、核销和买单事件，通过Event区分
+type CardEvent struct {
+	CommonToken
+	Event EventType
+	CardInfo
+}
+
+//UnknownMessage 没有封装的消息或事件，字段从Raw中读取
+type UnknownMessage struct {
+	CommonToken
+	Event EventType
+	Raw   []byte
+}
+
+//Typed 转换为具体类型的消息或事件
+func (msg *MixMessage) Typed() Message {
+	common := msg.CommonToken
+	switch msg.MsgType {
+	case MsgTypeText:
+		return &TextMessage{CommonToken: common, MsgID: msg.MsgID, Content: msg.Content}
+	case MsgTypeImage:
+		return &ImageMessage{CommonToken: common, MsgID: msg.MsgID, PicURL: msg.PicURL, MediaID: msg.MediaID}
+	case MsgTypeVoice:
+		return &VoiceMessage{CommonToken: common, MsgID: msg.MsgID, MediaID: msg.MediaID, Format: msg.Format, Recognition: msg.Recognition}
+	case MsgTypeVideo, MsgTypeShortVideo:
+		return &VideoMessage{CommonToken: common, MsgID: msg.MsgID, MediaID: msg.MediaID, ThumbMediaID: msg.ThumbMediaID}
+	case MsgTypeLocation:
+		return &LocationMessage{CommonToken: common, MsgID: msg.MsgID, LocationX: msg.LocationX, LocationY: msg.LocationY, Scale: msg.Scale, Label: msg.Label}
+	case MsgTypeLink:
+		return &LinkMessage{CommonToken: common, MsgID: msg.MsgID, Title: msg.Title, Description: msg.Description, URL: msg.URL}
+	case MsgTypeEvent:
+		if event := msg.typedEvent(); event != nil {
+			return event
+		}
+	}
+	return &UnknownMessage{CommonToken: common, Event: msg.Event, Raw: msg.Raw}
+}
+
+//typedEvent 没有封装的事件返回nil
+func (msg *MixMessage) typedEvent() Message {
+	common := msg.CommonToken
+	switch msg.Event {
+	case EventSubscribe:
+		event := &SubscribeEvent{CommonToken: common, EventKey: msg.EventKey, Ticket: msg.Ticket}
+		if strings.HasPrefix(msg.EventKey, "qrscene_") {
+			event.Scene = msg.EventKey[len("qrscene_"):]
+		}
+		return event
+	case EventUnsubscribe:
+		return &UnsubscribeEvent{CommonToken: common}
+	case EventScan:
+		return &ScanEvent{CommonToken: common, Scene: msg.EventKey, Ticket: msg.Ticket}
+	case EventLocation:
+		return &LocationEvent{CommonToken: common, Latitude: parseFloat(msg.Latitude), Longitude: parseFloat(msg.Longitude), Precision: parseFloat(msg.Precision)}
+	case EventClick:
+		return &ClickEvent{CommonToken: common, EventKey: msg.EventKey}
+	case EventView:
+		return &ViewEvent{CommonToken: common, URL: msg.EventKey, MenuID: msg.MenuID}
+	case EventViewMiniprogram:
+		return &ViewMiniprogramEvent{CommonToken: common, PagePath: msg.EventKey, MenuID: msg.MenuID}
+	case EventScancodePush, EventScancodeWaitmsg:
+		return &ScanCodeEvent{CommonToken: common, Event: msg.Event, EventKey: msg.EventKey, ScanCodeInfo: msg.ScanCodeInfo}
+	case EventPicSysphoto, EventPicPhotoOrAlbum, EventPicWeixin:
+		return &PicEvent{CommonToken: common, Event: msg.Event, EventKey: msg.EventKey, SendPicsInfo: msg.SendPicsInfo}
+	case EventLocationSelect:
+		return &LocationSelectEvent{CommonToken: common, EventKey: msg.EventKey, SendLocationInfo: msg.SendLocationInfo}
+	case EventTempLateSendJobFinish:
+		return &TemplateSendJobFinishEvent{CommonToken: common, MsgID: msg.JobMsgID, Status: msg.Status}
+	case EventMassSendJobFinish:
+		return &MassSendJobFinishEvent{CommonToken: common, MsgID: msg.JobMsgID, Status: msg.Status,
+			TotalCount: msg.TotalCount, FilterCount: msg.FilterCount, SentCount: msg.SentCount, ErrorCount: msg.ErrorCount}
+	case EventSubscribeMsgPopup:
+		return &SubscribeMsgEvent{CommonToken: common, Event: msg.Event, List: msg.SubscribeMsgPopupEvent}
+	case EventSubscribeMsgChange:
+		return &SubscribeMsgEvent{CommonToken: common, Event: msg.Event, List: msg.SubscribeMsgChangeEvent}
+	case EventSubscribeMsgSent:
+		return &SubscribeMsgEvent{CommonToken: common, Event: msg.Event, List: msg.SubscribeMsgSentEvent}
+	case EventCardPassCheck, EventCardNotPassCheck, EventUserGetCard, EventUserDelCard, EventUserConsumeCard, EventUserPayFromPayCell:
+		return &CardEvent{CommonToken: common, Event: msg.Event, CardInfo: msg.CardInfo}
+	}
+	return nil
+}
+
+func parseFloat(s string) float64 {
+	f, _ := strconv.ParseFloat(s, 64)
+	return f
+}
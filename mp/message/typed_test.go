@@ -0,0 +1,71 @@
+package message
+
+import (
+	"reflect"
+	"testing"
+)
+
+func push(msgType, body string) []byte {
+	return []byte("<xml><ToUserName><![CDATA[gh_x]]></ToUserName><FromUserName><![CDATA[user1]]></FromUserName>" +
+		"<CreateTime>1600000000</CreateTime><MsgType><![CDATA[" + msgType + "]]></MsgType>" + body + "</xml>")
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		raw  []byte
+		want Message
+	}{
+		{push("text", "<Content><![CDATA[hello]]></Content><MsgId>1</MsgId>"),
+			&TextMessage{MsgID: 1, Content: "hello"}},
+		{push("voice", "<MediaId>m</MediaId><Format>amr</Format><Recognition><![CDATA[你好]]></Recognition><MsgId>2</MsgId>"),
+			&VoiceMessage{MsgID: 2, MediaID: "m", Format: "amr", Recognition: "你好"}},
+		{push("event", "<Event>subscribe</Event><EventKey>qrscene_123</EventKey><Ticket>t</Ticket>"),
+			&SubscribeEvent{EventKey: "qrscene_123", Ticket: "t", Scene: "123"}},
+		{push("event", "<Event>subscribe</Event>"),
+			&SubscribeEvent{}},
+		{push("event", "<Event>LOCATION</Event><Latitude>23.137466</Latitude><Longitude>113.352425</Longitude><Precision>119.385040</Precision>"),
+			&LocationEvent{Latitude: 23.137466, Longitude: 113.352425, Precision: 119.38504}},
+		{push("event", "<Event>view_miniprogram</Event><EventKey>pages/index</EventKey><MenuId>1</MenuId>"),
+			&ViewMiniprogramEvent{PagePath: "pages/index", MenuID: "1"}},
+		{push("event", "<Event>TEMPLATESENDJOBFINISH</Event><MsgID>200163836</MsgID><Status>failed:user block</Status>"),
+			&TemplateSendJobFinishEvent{MsgID: 200163836, Status: "failed:user block"}},
+		{push("event", "<Event>MASSSENDJOBFINISH</Event><MsgID>1988</MsgID><Status>send success</Status>"+
+			"<TotalCount>100</TotalCount><FilterCount>80</FilterCount><SentCount>75</SentCount><ErrorCount>5</ErrorCount>"),
+			&MassSendJobFinishEvent{MsgID: 1988, Status: "send success", TotalCount: 100, FilterCount: 80, SentCount: 75, ErrorCount: 5}},
+		{push("event", "<Event>subscribe_msg_popup_event</Event><SubscribeMsgPopupEvent>"+
+			"<List><TemplateId>t1</TemplateId><SubscribeStatusString>accept</SubscribeStatusString><PopupScene>2</PopupScene></List>"+
+			"<List><TemplateId>t2</TemplateId><SubscribeStatusString>reject</SubscribeStatusString><PopupScene>2</PopupScene></List>"+
+			"</SubscribeMsgPopupEvent>"),
+			&SubscribeMsgEvent{Event: EventSubscribeMsgPopup, List: []SubscribeMsgItem{
+				{TemplateID: "t1", SubscribeStatusString: "accept", PopupScene: 2},
+				{TemplateID: "t2", SubscribeStatusString: "reject", PopupScene: 2}}}},
+		{push("event", "<Event>user_get_card</Event><CardId>c</CardId><UserCardCode>123</UserCardCode><IsGiveByFriend>1</IsGiveByFriend>"),
+			&CardEvent{Event: EventUserGetCard, CardInfo: CardInfo{CardID: "c", UserCardCode: "123", IsGiveByFriend: 1}}},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.raw)
+		if err != nil {
+			t.Fatal(err)
+		}
+		common := *got.Common()
+		if common.FromUserName != "user1" || common.CreateTime != 1600000000 {
+			t.Errorf("%s: unexpected common fields %+v", tt.raw, common)
+		}
+		*got.Common() = CommonToken{}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s:\ngot  %+v\nwant %+v", tt.raw, got, tt.want)
+		}
+	}
+}
+
+func TestParseUnknown(t *testing.T) {
+	raw := push("event", "<Event>some_new_event</Event><Foo>bar</Foo>")
+	got, err := Parse(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	unknown, ok := got.(*UnknownMessage)
+	if !ok || unknown.Event != "some_new_event" || string(unknown.Raw) != string(raw) {
+		t.Errorf("unexpected %#v", got)
+	}
+}
@@ -1,6 +1,7 @@
 package oauth
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -34,7 +35,7 @@ func NewOauth(context *wxcontext.Context) *Oauth {
 func (oauth *Oauth) GetRedirectURL(redirectURI, scope, state string) string {
 	//url encode
 	urlStr := url.QueryEscape(redirectURI)
-	return fmt.Sprintf(redirectOauthURL, oauth.AppID, urlStr, scope, state)
+	return oauth.ResolveURL(fmt.Sprintf(redirectOauthURL, oauth.AppID, urlStr, scope, state))
 }
 
 //Redirect 跳转到网页授权
@@ -58,9 +59,14 @@ type ResAccessToken struct {
 
 // GetUserAccessToken 通过网页授权的code 换取access_token(区别于context中的access_token)
 func (oauth *Oauth) GetUserAccessToken(code string) (result ResAccessToken, err error) {
-	urlStr := fmt.Sprintf(accessTokenURL, oauth.AppID, oauth.AppSecret, code)
+	return oauth.GetUserAccessTokenContext(context.Background(), code)
+}
+
+//GetUserAccessTokenContext 同 GetUserAccessToken，支持 context
+func (oauth *Oauth) GetUserAccessTokenContext(ctx context.Context, code string) (result ResAccessToken, err error) {
+	urlStr := oauth.ResolveURL(fmt.Sprintf(accessTokenURL, oauth.AppID, oauth.AppSecret, code))
 	var response []byte
-	response, err = util.HTTPGet(urlStr)
+	response, err = oauth.HTTPGetContext(ctx, urlStr)
 	if err != nil {
 		return
 	}
@@ -69,7 +75,7 @@ func (oauth *Oauth) GetUserAccessToken(code string) (result ResAccessToken, err
 		return
 	}
 	if result.ErrCode != 0 {
-		err = fmt.Errorf("GetUserAccessToken error : errcode=%v , errmsg=%v", result.ErrCode, result.ErrMsg)
+		err = util.NewAPIError(accessTokenURL, result.ErrCode, result.ErrMsg)
 		return
 	}
 	return
@@ -77,9 +83,14 @@ func (oauth *Oauth) GetUserAccessToken(code string) (result ResAccessToken, err
 
 //RefreshAccessToken 刷新access_token
 func (oauth *Oauth) RefreshAccessToken(refreshToken string) (result ResAccessToken, err error) {
-	urlStr := fmt.Sprintf(refreshAccessTokenURL, oauth.AppID, refreshToken)
+	return oauth.RefreshAccessTokenContext(context.Background(), refreshToken)
+}
+
+//RefreshAccessTokenContext 同 RefreshAccessToken，支持 context
+func (oauth *Oauth) RefreshAccessTokenContext(ctx context.Context, refreshToken string) (result ResAccessToken, err error) {
+	urlStr := oauth.ResolveURL(fmt.Sprintf(refreshAccessTokenURL, oauth.AppID, refreshToken))
 	var response []byte
-	response, err = util.HTTPGet(urlStr)
+	response, err = oauth.HTTPGetContext(ctx, urlStr)
 	if err != nil {
 		return
 	}
@@ -88,7 +99,7 @@ func (oauth *Oauth) RefreshAccessToken(refreshToken string) (result ResAccessTok
 		return
 	}
 	if result.ErrCode != 0 {
-		err = fmt.Errorf("GetUserAccessToken error : errcode=%v , errmsg=%v", result.ErrCode, result.ErrMsg)
+		err = util.NewAPIError(refreshAccessTokenURL, result.ErrCode, result.ErrMsg)
 		return
 	}
 	return
@@ -96,9 +107,14 @@ func (oauth *Oauth) RefreshAccessToken(refreshToken string) (result ResAccessTok
 
 //CheckAccessToken 检验access_token是否有效
 func (oauth *Oauth) CheckAccessToken(accessToken, openID string) (b bool, err error) {
-	urlStr := fmt.Sprintf(checkAccessTokenURL, accessToken, openID)
+	return oauth.CheckAccessTokenContext(context.Background(), accessToken, openID)
+}
+
+//CheckAccessTokenContext 同 CheckAccessToken，支持 context
+func (oauth *Oauth) CheckAccessTokenContext(ctx context.Context, accessToken, openID string) (b bool, err error) {
+	urlStr := oauth.ResolveURL(fmt.Sprintf(checkAccessTokenURL, accessToken, openID))
 	var response []byte
-	response, err = util.HTTPGet(urlStr)
+	response, err = oauth.HTTPGetContext(ctx, urlStr)
 	if err != nil {
 		return
 	}
@@ -132,9 +148,14 @@ type UserInfo struct {
 
 //GetUserInfo 如果scope为 snsapi_userinfo 则可以通过此方法获取到用户基本信息
 func (oauth *Oauth) GetUserInfo(accessToken, openID string) (result UserInfo, err error) {
-	urlStr := fmt.Sprintf(userInfoURL, accessToken, openID)
+	return oauth.GetUserInfoContext(context.Background(), accessToken, openID)
+}
+
+//GetUserInfoContext 同 GetUserInfo，支持 context
+func (oauth *Oauth) GetUserInfoContext(ctx context.Context, accessToken, openID string) (result UserInfo, err error) {
+	urlStr := oauth.ResolveURL(fmt.Sprintf(userInfoURL, accessToken, openID))
 	var response []byte
-	response, err = util.HTTPGet(urlStr)
+	response, err = oauth.HTTPGetContext(ctx, urlStr)
 	if err != nil {
 		return
 	}
@@ -143,7 +164,7 @@ func (oauth *Oauth) GetUserInfo(accessToken, openID string) (result UserInfo, er
 		return
 	}
 	if result.ErrCode != 0 {
-		err = fmt.Errorf("GetUserInfo error : errcode=%v , errmsg=%v", result.ErrCode, result.ErrMsg)
+		err = util.NewAPIError(userInfoURL, result.ErrCode, result.ErrMsg)
 		return
 	}
 	return
@@ -0,0 +1,77 @@
+package quota
+
+import (
+	"context"
+
+	"github.com/MrCHI/gowechat/mp/base"
+	"github.com/MrCHI/gowechat/util"
+	"github.com/MrCHI/gowechat/wxcontext"
+)
+
+const (
+	clearQuotaURL = "https://api.weixin.qq.com/cgi-bin/clear_quota"
+	getQuotaURL   = "https://api.weixin.qq.com/cgi-bin/openapi/quota/get"
+)
+
+//Quota 接口调用次数管理
+type Quota struct {
+	base.MpBase
+}
+
+//NewQuota 实例化
+func NewQuota(context *wxcontext.Context) *Quota {
+	quota := new(Quota)
+	quota.Context = context
+	return quota
+}
+
+//Info 接口的调用次数
+type Info struct {
+	DailyLimit int64 `json:"daily_limit"` // 当天该账号可调用该接口的次数
+	Used       int64 `json:"used"`        // 当天已经调用的次数
+	Remain     int64 `json:"remain"`      // 当天剩余调用次数
+}
+
+//reqQuota 查询调用次数的请求数据
+type reqQuota struct {
+	CgiPath string `json:"cgi_path"`
+}
+
+//resQuota 查询调用次数的返回数据
+type resQuota struct {
+	util.CommonError
+
+	Quota Info `json:"quota"`
+}
+
+//Clear 公众号所有API调用次数清零（每月10次），成功后同时清空本地的计数
+func (quota *Quota) Clear() error {
+	return quota.ClearContext(context.Background())
+}
+
+//ClearContext 同 Clear，支持 context
+func (quota *Quota) ClearContext(ctx context.Context) error {
+	req := map[string]string{
+		"appid": quota.AppID,
+	}
+	if _, err := quota.HTTPPostJSONWithAccessTokenContext(ctx, clearQuotaURL, req); err != nil {
+		return err
+	}
+	return quota.ResetQuotaUsage()
+}
+
+//Get 查询接口当天的调用次数，cgiPath为接口路径，例如 /cgi-bin/message/custom/send
+func (quota *Quota) Get(cgiPath string) (info Info, err error) {
+	return quota.GetContext(context.Background(), cgiPath)
+}
+
+//GetContext 同 Get，支持 context
+func (quota *Quota) GetContext(ctx context.Context, cgiPath string) (info Info, err error) {
+	res, err := base.CallContext[*reqQuota, resQuota](ctx, &quota.MpBase, getQuotaURL, &reqQuota{CgiPath: cgiPath})
+	return res.Quota, err
+}
+
+//Usage 本地记录的当天调用情况，不需要请求微信服务器
+func (quota *Quota) Usage(cgiPath string) wxcontext.QuotaUsage {
+	return quota.GetQuotaUsage(cgiPath)
+}
@@ -1,7 +1,7 @@
 package template
 
 import (
-	"encoding/json"
+	"context"
 
 	"github.com/MrCHI/gowechat/mp/base"
 	"github.com/MrCHI/gowechat/util"
@@ -57,22 +57,13 @@ type resTemplateSend struct {
 
 //Send 发送模板消息
 func (tpl *Template) Send(msg *Message) (msgID int64, err error) {
-	response, err := tpl.HTTPPostJSONWithAccessToken(templateSendURL, msg)
-
-	if err != nil {
-		return 0, err
-	}
-
-	var result resTemplateSend
-	err = json.Unmarshal(response, &result)
-
-	if err != nil {
-		return 0, err
-	}
-
-	msgID = result.MsgID
+	return tpl.SendContext(context.Background(), msg)
+}
 
-	return 0, nil
+//SendContext 同 Send，支持 context
+func (tpl *Template) SendContext(ctx context.Context, msg *Message) (msgID int64, err error) {
+	res, err := base.CallContext[*Message, resTemplateSend](ctx, &tpl.MpBase, templateSendURL, msg)
+	return res.MsgID, err
 }
 
 //IndustryList 行业列表
@@ -102,42 +93,45 @@ type TmplList struct {
 
 //AddTemplate 增加一个模板
 func (tpl *Template) AddTemplate(templateIDShort string) (templateID string, err error) {
+	return tpl.AddTemplateContext(context.Background(), templateIDShort)
+}
+
+//AddTemplateContext 同 AddTemplate，支持 context
+func (tpl *Template) AddTemplateContext(ctx context.Context, templateIDShort string) (templateID string, err error) {
 	type reqAddTmpl struct {
 		TemplateIDShort string `json:"template_id_short"`
 	}
-	var response []byte
-	response, err = tpl.HTTPPostJSONWithAccessToken(templateAddURL, reqAddTmpl{TemplateIDShort: templateIDShort})
-	if err != nil {
-		return "", err
-	}
-
-	var result Tmpl
-	err = json.Unmarshal(response, &result)
-	if err != nil {
-		return
-	}
-	templateID = result.TemplateId
-	return
+	res, err := base.CallContext[reqAddTmpl, Tmpl](ctx, &tpl.MpBase, templateAddURL, reqAddTmpl{TemplateIDShort: templateIDShort})
+	return res.TemplateId, err
 }
 
 //GetTemplateList 查询模板列表
 func (tpl *Template) GetTemplateList(templateIDShort string) (list TmplList, err error) {
-	var response []byte
-	response, err = tpl.HTTPGetWithAccessToken(templateAllURL)
-	err = json.Unmarshal(response, &list)
-	return
+	return tpl.GetTemplateListContext(context.Background(), templateIDShort)
+}
+
+//GetTemplateListContext 同 GetTemplateList，支持 context
+func (tpl *Template) GetTemplateListContext(ctx context.Context, templateIDShort string) (list TmplList, err error) {
+	return base.GetContext[TmplList](ctx, &tpl.MpBase, templateAllURL)
 }
 
 //GetTemplateIndustry 获得模板行业
 func (tpl *Template) GetTemplateIndustry() (industryList IndustryList, err error) {
-	var response []byte
-	response, err = tpl.HTTPGetWithAccessToken(templateGetIndustryURL)
-	err = json.Unmarshal(response, &industryList)
-	return
+	return tpl.GetTemplateIndustryContext(context.Background())
+}
+
+//GetTemplateIndustryContext 同 GetTemplateIndustry，支持 context
+func (tpl *Template) GetTemplateIndustryContext(ctx context.Context) (industryList IndustryList, err error) {
+	return base.GetContext[IndustryList](ctx, &tpl.MpBase, templateGetIndustryURL)
 }
 
 //SetTemplateIndustry 设置模板行业
 func (tpl *Template) SetTemplateIndustry(industry1, industry2 int) (err error) {
+	return tpl.SetTemplateIndustryContext(context.Background(), industry1, industry2)
+}
+
+//SetTemplateIndustryContext 同 SetTemplateIndustry，支持 context
+func (tpl *Template) SetTemplateIndustryContext(ctx context.Context, industry1, industry2 int) (err error) {
 	type reqSetIndustry struct {
 		Industry1 int `json:"industry_id1"`
 		Industry2 int `json:"industry_id2"`
@@ -149,6 +143,6 @@ func (tpl *Template) SetTemplateIndustry(industry1, industry2 int) (err error) {
 	if industry2 > 0 {
 		req.Industry2 = industry2
 	}
-	_, err = tpl.HTTPPostJSONWithAccessToken(templateSetIndustryURL, req)
+	_, err = tpl.HTTPPostJSONWithAccessTokenContext(ctx, templateSetIndustryURL, req)
 	return
 }
@@ -1,7 +1,7 @@
 package user
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
 
 	"github.com/MrCHI/gowechat/mp/base"
@@ -47,21 +47,24 @@ type Info struct {
 
 //GetUserInfo 获取用户基本信息
 func (user *User) GetUserInfo(openID string) (userInfo *Info, err error) {
+	return user.GetUserInfoContext(context.Background(), openID)
+}
+
+//GetUserInfoContext 同 GetUserInfo，支持 context
+func (user *User) GetUserInfoContext(ctx context.Context, openID string) (userInfo *Info, err error) {
 	url := fmt.Sprintf("%s?openid=%s&lang=zh_CN", userInfoURL, openID)
-	var response []byte
-	response, err = user.HTTPGetWithAccessToken(url)
-	if err != nil {
-		return
-	}
-	userInfo = new(Info)
-	err = json.Unmarshal(response, userInfo)
-	return
+	return base.GetContext[*Info](ctx, &user.MpBase, url)
 }
 
 //IsSubscribed 是否已经关注公众号
 func (user *User) IsSubscribed(openID string) (subscribed bool, err error) {
+	return user.IsSubscribedContext(context.Background(), openID)
+}
+
+//IsSubscribedContext 同 IsSubscribed，支持 context
+func (user *User) IsSubscribedContext(ctx context.Context, openID string) (subscribed bool, err error) {
 	var userInfo *Info
-	userInfo, err = user.GetUserInfo(openID)
+	userInfo, err = user.GetUserInfoContext(ctx, openID)
 	if err != nil {
 		return
 	}
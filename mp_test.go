@@ -1,20 +1,59 @@
 package gowechat
 
 import (
+	"context"
+	"strings"
 	"testing"
 
-	"github.com/astaxie/beego"
+	"github.com/MrCHI/gowechat/cache"
+	"github.com/MrCHI/gowechat/httpreplay"
 	"github.com/MrCHI/gowechat/wxcontext"
 )
 
 func TestGetQrcode(t *testing.T) {
+	replayer, err := httpreplay.NewReplayer("testdata/qrcode.json")
+	if err != nil {
+		t.Fatal(err)
+	}
 	config := wxcontext.Config{
-		AppID:     "wx88c493c9a9f67ea6",
-		AppSecret: "0c1fe2db856c60d9c52b65383feadae1",
-		Token:     "zyt2864",
+		AppID:           "wx0000000000000000",
+		AppSecret:       "secret",
+		Token:           "token",
+		HTTPMiddlewares: []wxcontext.HTTPMiddleware{replayer.Middleware},
 	}
 	wc := NewWechat(config)
-	beego.Debug("wechat's cache:", wc.Context.Cache)
-	mp, _ := wc.MpMgr()
-	mp.GetQrcode().CreatePermanentQRCodeWithSceneString("test")
+	t.Log("wechat's cache:", wc.Context.Cache)
+	mp, err := wc.MpMgr()
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := mp.GetQrcode().CreatePermanentQRCodeWithSceneString("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.URL != "http://weixin.qq.com/q/02mJcSYdVvd0h10000w07T" {
+		t.Errorf("unexpected qrcode url %q", result.URL)
+	}
+	if n := replayer.Remaining(); n != 0 {
+		t.Errorf("%d recorded requests not used", n)
+	}
+}
+
+func TestWarnCacheWithoutLocker(t *testing.T) {
+	var warnings int
+	logger := wxcontext.LoggerFunc(func(ctx context.Context, level wxcontext.LogLevel, msg string, keyvals ...interface{}) {
+		if level == wxcontext.LevelWarn && strings.Contains(msg, "cache.Locker") {
+			warnings++
+		}
+	})
+	m := cache.NewMemory(0)
+	defer m.Close()
+	plain := struct{ cache.Cache }{m}
+	for i := 0; i < 2; i++ {
+		NewWechat(wxcontext.Config{AppID: "appid", Cache: plain, Logger: logger})
+		NewWechat(wxcontext.Config{AppID: "appid", Cache: m, Logger: logger})
+	}
+	if warnings != 1 {
+		t.Errorf("got %d warnings, want one for the cache without Locker", warnings)
+	}
 }
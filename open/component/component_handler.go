@@ -1,18 +1,20 @@
 package component
 
 import (
+	"context"
 	"encoding/json"
 	"encoding/xml"
 	"errors"
 	"fmt"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
-	"github.com/MrCHI/WXBizMsgCrypt"
-
+	"github.com/MrCHI/gowechat/cache"
+	"github.com/MrCHI/gowechat/util"
 	"github.com/MrCHI/gowechat/wxcontext"
 
-	"models.umilive.com/module_common"
-
 	"github.com/MrCHI/gowechat/open/base"
 )
 
@@ -24,16 +26,21 @@ const (
 	getComponentInfoURL     = "https://api.weixin.qq.com/cgi-bin/component/api_get_authorizer_info?component_access_token=%s"
 	getComponentConfigURL   = "https://api.weixin.qq.com/cgi-bin/component/api_get_authorizer_option?component_access_token=%s"
 	bindComponentURL        = "https://mp.weixin.qq.com/safe/bindcomponent?action=%s"
+	clearQuotaURL           = "https://api.weixin.qq.com/cgi-bin/component/clear_quota?component_access_token=%s"
 )
 
 type Component struct {
 	base.OpenBase
+	// ticketLock 保护componentVerifyTicket，推送和后台刷新在不同的goroutine中读写
+	ticketLock            sync.Mutex
 	componentVerifyTicket string
 	componentAccessToken  string
 	authorizationCode     string
-	cryptTools            WXBizMsgCrypt.WXBizMsgCrypt // 加密、解密
 }
 
+// component_verify_ticket 的有效期
+const componentVerifyTicketExpires = 12 * time.Hour
+
 func NewComponent(context *wxcontext.Context) *Component {
 	component := new(Component)
 	component.Context = context
@@ -41,105 +48,194 @@ func NewComponent(context *wxcontext.Context) *Component {
 }
 
 // 处理微信10分钟1次的推送消息
+//
+// bodyEncrypt 可以是推送的整个xml，也可以只是其中Encrypt的内容；msgSign、timestamp、nonce 取自url参数
 func (_this *Component) HandlerCallBack(bodyEncrypt string, nonce string, encryptType string, msgSign string, timestamp int64) (*AuthNotifyResponse, error) {
-	fmt.Printf("收到微信开放平台推送消息，10分钟/次\n")
+	return _this.HandlerCallBackContext(context.Background(), bodyEncrypt, nonce, encryptType, msgSign, timestamp)
+}
 
-	if _this.cryptTools == nil {
-		_this.cryptTools, _ = WXBizMsgCrypt.NewWXBizMsgCrypt(_this.ComponentAppToken, _this.ComponentAppKey, _this.ComponentAppId)
-	}
+// 同 HandlerCallBack，支持 context
+func (_this *Component) HandlerCallBackContext(ctx context.Context, bodyEncrypt string, nonce string, encryptType string, msgSign string, timestamp int64) (*AuthNotifyResponse, error) {
+	_this.Log(ctx, wxcontext.LevelDebug, "收到微信开放平台推送消息，10分钟/次")
 
-	result, decryp_xml := _this.cryptTools.DecryptMsg(bodyEncrypt, msgSign, timestamp, nonce)
+	decryptXML, err := _this.decryptMsg(bodyEncrypt, nonce, encryptType, msgSign, timestamp)
 
-	if result != 0 {
-		return nil, errors.New(fmt.Sprintf("decryp error:%v", result))
+	if err != nil {
+		return nil, err
 	}
 
 	// 提取信息
 	authNotify := &AuthNotifyResponse{}
-	err := xml.Unmarshal([]byte(decryp_xml), authNotify)
+	err = xml.Unmarshal(decryptXML, authNotify)
 
 	if err != nil {
 		return nil, err
 	}
 
-	fmt.Printf("AppId:%v\n", authNotify.AppID)
-	fmt.Printf("ComponentVerifyTicket:%v\n", authNotify.ComponentVerifyTicket)
+	_this.Log(ctx, wxcontext.LevelInfo, "收到component_verify_ticket", "component_appid", authNotify.AppID, "component_verify_ticket", authNotify.ComponentVerifyTicket)
 
 	// 通过APPID过虑
 	if _this.Context.ComponentAppId != authNotify.AppID {
 		return nil, fmt.Errorf("%v", "app_id is invalid.")
 	}
 
-	// 更新Ticket内容
+	// 更新Ticket内容，写入缓存供其他实例、进程使用
+	_this.ticketLock.Lock()
 	_this.componentVerifyTicket = authNotify.ComponentVerifyTicket
+	_this.ticketLock.Unlock()
+	err = _this.Context.Cache.Put(_this.verifyTicketCacheKey(), authNotify.ComponentVerifyTicket, componentVerifyTicketExpires)
+
+	if err != nil {
+		return nil, err
+	}
 
 	// 获取开放平台开发者凭据
-	_this.GetComponentAccessToken(_this.componentVerifyTicket)
+	if _, err = _this.GetComponentAccessTokenContext(ctx, _this.verifyTicket()); err != nil {
+		_this.Log(ctx, wxcontext.LevelError, "获取第三方平台开发者凭据失败", "error", err)
+	}
 
 	return authNotify, nil
 }
 
+// 校验签名并解密推送消息
+func (_this *Component) decryptMsg(bodyEncrypt string, nonce string, encryptType string, msgSign string, timestamp int64) ([]byte, error) {
+	if encryptType != "" && encryptType != "aes" {
+		return nil, fmt.Errorf("unsupported encrypt_type: %s", encryptType)
+	}
+
+	encrypted := strings.TrimSpace(bodyEncrypt)
+	if strings.HasPrefix(encrypted, "<") {
+		encMessage := &EncMessage{}
+		if err := xml.Unmarshal([]byte(encrypted), encMessage); err != nil {
+			return nil, fmt.Errorf("从body中解析xml失败, err=%v", err)
+		}
+		encrypted = encMessage.Encrypt
+	}
+
+	if msgSign != util.Signature(_this.ComponentAppToken, strconv.FormatInt(timestamp, 10), nonce, encrypted) {
+		return nil, errors.New("消息不合法，验证签名失败")
+	}
+
+	_, rawXML, err := util.DecryptMsg(_this.ComponentAppId, encrypted, _this.ComponentAppKey)
+
+	if err != nil {
+		return nil, fmt.Errorf("消息解密失败, err=%v", err)
+	}
+
+	return rawXML, nil
+}
+
+// 最近一次推送的component_verify_ticket，优先从缓存读取（可能由其他实例、进程收到）
+func (_this *Component) verifyTicket() string {
+	if ticket := cache.GetString(_this.Context.Cache, _this.verifyTicketCacheKey()); ticket != "" {
+		return ticket
+	}
+	_this.ticketLock.Lock()
+	defer _this.ticketLock.Unlock()
+	return _this.componentVerifyTicket
+}
+
+func (_this *Component) verifyTicketCacheKey() string {
+	return fmt.Sprintf("component_verify_ticket_%s", _this.ComponentAppId)
+}
+
 // 获取第三方平台开发者凭据
 func (_this *Component) GetComponentAccessToken(componentVerifyTicket string) (access_token *ApiComponentTokenResponse, e error) {
-	fmt.Printf("获取第三方平台开发者凭据\n")
+	return _this.GetComponentAccessTokenContext(context.Background(), componentVerifyTicket)
+}
 
+// 同 GetComponentAccessToken，支持 context
+func (_this *Component) GetComponentAccessTokenContext(ctx context.Context, componentVerifyTicket string) (access_token *ApiComponentTokenResponse, e error) {
 	if componentVerifyTicket == "" {
 		return nil, errors.New("component_verify_ticket is invalid.")
 	}
 
 	// 优先从缓存中读取
 	component_access_token_key := fmt.Sprintf("component_access_token_%s", _this.Context.AppID)
-	component_access_token := _this.Context.Cache.Get(component_access_token_key)
-
-	if component_access_token != nil {
-		return component_access_token.(*ApiComponentTokenResponse), nil
+	cachedToken := &ApiComponentTokenResponse{}
+	if cache.GetJSON(_this.Context.Cache, component_access_token_key, cachedToken) {
+		return cachedToken, nil
 	}
 
 	// 从微信服务器获取
-	jsonData := ApiComponentTokenRequest{
-		ComponentAppId:        _this.ComponentAppId,
-		ComponentAppSecret:    _this.ComponentAppSecret,
-		ComponentVerifyTicket: componentVerifyTicket,
-	}
-
-	jsonString, err := json.Marshal(jsonData)
+	componentToken, err := _this.requestComponentAccessToken(ctx, componentVerifyTicket)
 
 	if err != nil {
 		return nil, err
 	}
 
-	result, err := module_common.HttpPostJson(componentAccessTokenURL, string(jsonString))
+	// 写入缓存
+	err = cache.PutJSON(_this.Context.Cache, component_access_token_key, componentToken, componentTokenExpires(componentToken.ExpiresIn))
 
 	if err != nil {
 		return nil, err
 	}
 
-	componentToken := &ApiComponentTokenResponse{}
-	err = json.Unmarshal([]byte(result), componentToken)
+	return componentToken, nil
+}
+
+// 供 wxcontext.Refresher 使用，提前刷新component_access_token
+func (_this *Component) RefreshTask() wxcontext.RefreshTask {
+	return wxcontext.RefreshTask{Name: "component_access_token", Refresh: func(ctx context.Context) (time.Duration, error) {
+		componentVerifyTicket := _this.verifyTicket()
+		if componentVerifyTicket == "" {
+			return 0, errors.New("component_verify_ticket is invalid.")
+		}
+
+		component_access_token_key := fmt.Sprintf("component_access_token_%s", _this.Context.AppID)
+		return _this.RenewToken(ctx, component_access_token_key, func(ctx context.Context) (string, time.Duration, error) {
+			componentToken, err := _this.requestComponentAccessToken(ctx, componentVerifyTicket)
+			if err != nil {
+				return "", 0, err
+			}
+			data, err := json.Marshal(componentToken)
+			if err != nil {
+				return "", 0, err
+			}
+			return string(data), componentTokenExpires(componentToken.ExpiresIn), nil
+		})
+	}}
+}
+
+// 从微信服务器获取第三方平台开发者凭据，不写缓存
+func (_this *Component) requestComponentAccessToken(ctx context.Context, componentVerifyTicket string) (*ApiComponentTokenResponse, error) {
+	jsonData := ApiComponentTokenRequest{
+		ComponentAppId:        _this.ComponentAppId,
+		ComponentAppSecret:    _this.ComponentAppSecret,
+		ComponentVerifyTicket: componentVerifyTicket,
+	}
+
+	result, err := _this.postJSON(ctx, componentAccessTokenURL, jsonData)
 
 	if err != nil {
 		return nil, err
 	}
 
-	fmt.Printf("expires_in:%v\n", componentToken.ExpiresIn)
-	fmt.Printf("component_access_token:%v\n", componentToken.ComponentAccessToken)
-
-	// 写入缓存
-	expires := componentToken.ExpiresIn - 1500
-	err = _this.Context.Cache.Put(component_access_token_key, componentToken, time.Duration(expires)*time.Second)
+	componentToken := &ApiComponentTokenResponse{}
+	err = json.Unmarshal(result, componentToken)
 
 	if err != nil {
 		return nil, err
 	}
 
+	_this.Log(ctx, wxcontext.LevelInfo, "获取第三方平台开发者凭据", "expires_in", componentToken.ExpiresIn, "component_access_token", componentToken.ComponentAccessToken)
+
 	return componentToken, nil
 }
 
+// 提前过期，留出刷新的时间
+func componentTokenExpires(expiresIn int64) time.Duration {
+	return time.Duration(expiresIn-1500) * time.Second
+}
+
 // 获取第三方平台开发者预授权码
 func (_this *Component) GetPreAuthCode() (*ApiCreatePreauthCodeResponse, error) {
-	fmt.Printf("获取第三方平台开发者预授权码\n")
+	return _this.GetPreAuthCodeContext(context.Background())
+}
 
-	componentAccessToken, err := _this.GetComponentAccessToken(_this.componentVerifyTicket)
+// 同 GetPreAuthCode，支持 context
+func (_this *Component) GetPreAuthCodeContext(ctx context.Context) (*ApiCreatePreauthCodeResponse, error) {
+	componentAccessToken, err := _this.GetComponentAccessTokenContext(ctx, _this.verifyTicket())
 
 	if err != nil {
 		return nil, errors.New("component_access_token is invalid.")
@@ -149,66 +245,64 @@ func (_this *Component) GetPreAuthCode() (*ApiCreatePreauthCodeResponse, error)
 		ComponentAppId: _this.ComponentAppId,
 	}
 
-	jsonString, err := json.Marshal(jsonData)
+	result, err := _this.postJSON(ctx, fmt.Sprintf(getPreCodeURL, componentAccessToken.ComponentAccessToken), jsonData)
 
 	if err != nil {
-		fmt.Println(err.Error())
-		return nil, err
-	}
-
-	result, err := module_common.HttpPostJson(getPreCodeURL+componentAccessToken.ComponentAccessToken, string(jsonString))
-
-	if err != nil {
-		fmt.Println(err.Error())
+		_this.Log(ctx, wxcontext.LevelError, "获取预授权码失败", "error", err)
 		return nil, err
 	}
 
 	preAuthCode := &ApiCreatePreauthCodeResponse{}
-	err = json.Unmarshal([]byte(result), preAuthCode)
+	err = json.Unmarshal(result, preAuthCode)
 
 	if err != nil {
-		fmt.Println(err.Error())
+		_this.Log(ctx, wxcontext.LevelError, "解析预授权码失败", "error", err)
 		return nil, err
 	}
 
-	fmt.Printf("expires_in:%v\n", preAuthCode.ExpiresIn)
-	fmt.Printf("pre_auth_code:%v\n", preAuthCode.PreAuthCode)
+	_this.Log(ctx, wxcontext.LevelDebug, "获取第三方平台开发者预授权码", "expires_in", preAuthCode.ExpiresIn, "pre_auth_code", preAuthCode.PreAuthCode)
 
 	return preAuthCode, nil
 }
 
 // 获取授权页面
 func (_this *Component) GetAuthWeb(preAuthCode string, bindComponentCallbackURL string) (string, error) {
-	url := fmt.Sprintf(bindComponentURL, "bindcomponent"+
+	url := _this.ResolveURL(fmt.Sprintf(bindComponentURL, "bindcomponent"+
 		"&auth_type=3"+
 		"&no_scan=1"+
 		"&component_appid="+_this.ComponentAppId+
 		"&pre_auth_code="+preAuthCode+
 		"&redirect_uri="+bindComponentCallbackURL+
 		"&auth_type=3"+
-		"#wechat_redirect")
+		"#wechat_redirect"))
 
 	return url, nil
 }
 
 // 授权后回调URI，得到授权码（authorization_code）和过期时间
 func (_this *Component) AuthWebCallback(auth_code string, expires_in int64) (authorizationCode string, authorizationExpiresIn int64) {
+	return _this.AuthWebCallbackContext(context.Background(), auth_code, expires_in)
+}
+
+// 同 AuthWebCallback，支持 context
+func (_this *Component) AuthWebCallbackContext(ctx context.Context, auth_code string, expires_in int64) (authorizationCode string, authorizationExpiresIn int64) {
 	_this.authorizationCode = auth_code
 	authorizationExpiresIn = expires_in
 
-	fmt.Printf("第三方同意授权\n")
-	fmt.Printf("authorization_code:%v\n", auth_code)
-	fmt.Printf("authorization_expires_in:%v\n", expires_in)
+	_this.Log(ctx, wxcontext.LevelInfo, "第三方同意授权", "authorization_code", auth_code, "authorization_expires_in", expires_in)
 
-	_this.QueryAuthCode(_this.authorizationCode, _this.componentAccessToken)
+	_this.QueryAuthCodeContext(ctx, _this.authorizationCode, _this.componentAccessToken)
 
 	return _this.authorizationCode, authorizationExpiresIn
 }
 
 // 使用授权码换取公众号或小程序的接口调用凭据和授权信息
 func (_this *Component) QueryAuthCode(authorizationCode string, componentAccessToken string) (*ApiQueryAuthResponse, error) {
-	fmt.Printf("使用授权码换取第三方公众号或小程序的接口调用凭据和授权信息\n")
+	return _this.QueryAuthCodeContext(context.Background(), authorizationCode, componentAccessToken)
+}
 
+// 同 QueryAuthCode，支持 context
+func (_this *Component) QueryAuthCodeContext(ctx context.Context, authorizationCode string, componentAccessToken string) (*ApiQueryAuthResponse, error) {
 	if authorizationCode == "" {
 		return nil, errors.New("authorization_code is invalid.")
 	}
@@ -219,10 +313,9 @@ func (_this *Component) QueryAuthCode(authorizationCode string, componentAccessT
 
 	// 优先从缓存中读取
 	authorizer_access_tokenn_key := fmt.Sprintf("authorizer_access_tokenn_key_%s", _this.Context.AppID)
-	authorizer_access_tokenn := _this.Context.Cache.Get(authorizer_access_tokenn_key)
-
-	if authorizer_access_tokenn != nil {
-		return authorizer_access_tokenn.(*ApiQueryAuthResponse), nil
+	cachedAuth := &ApiQueryAuthResponse{}
+	if cache.GetJSON(_this.Context.Cache, authorizer_access_tokenn_key, cachedAuth) {
+		return cachedAuth, nil
 	}
 
 	jsonData := ApiQueryAuthRequest{
@@ -230,30 +323,24 @@ func (_this *Component) QueryAuthCode(authorizationCode string, componentAccessT
 		AuthorizationCode: authorizationCode,
 	}
 
-	jsonString, err := json.Marshal(jsonData)
-
-	if err != nil {
-		return nil, err
-	}
-
-	result, err := module_common.HttpPostJson(queryAuthURL+componentAccessToken, string(jsonString))
+	result, err := _this.postJSON(ctx, fmt.Sprintf(queryAuthURL, componentAccessToken), jsonData)
 
 	if err != nil {
 		return nil, err
 	}
 
 	queryAuth := &ApiQueryAuthResponse{}
-	err = json.Unmarshal([]byte(result), queryAuth)
+	err = json.Unmarshal(result, queryAuth)
 
 	if err != nil {
 		return nil, err
 	}
 
-	fmt.Printf("cacheData.QueryAuth:%+v\n", queryAuth)
+	_this.Log(ctx, wxcontext.LevelInfo, "使用授权码换取接口调用凭据", "authorizer_appid", queryAuth.AuthorizationInfo.AuthorizerAppId, "expires_in", queryAuth.AuthorizationInfo.ExpiresIn)
 
 	// 写入缓存
 	expires := queryAuth.AuthorizationInfo.ExpiresIn - 1500
-	err = _this.Context.Cache.Put(authorizer_access_tokenn_key, queryAuth, time.Duration(expires)*time.Second)
+	err = cache.PutJSON(_this.Context.Cache, authorizer_access_tokenn_key, queryAuth, time.Duration(expires)*time.Second)
 
 	if err != nil {
 		return nil, err
@@ -264,47 +351,40 @@ func (_this *Component) QueryAuthCode(authorizationCode string, componentAccessT
 
 // 获取（刷新）授权公众号或小程序的接口调用凭据（令牌）
 func (_this *Component) RefreshAuthToken() (*ApiAuthorizerTokenResponse, error) {
-	fmt.Printf("获取（刷新）第三方授权公众号或小程序的接口调用凭据（令牌）\n")
+	return _this.RefreshAuthTokenContext(context.Background())
+}
 
+// 同 RefreshAuthToken，支持 context
+func (_this *Component) RefreshAuthTokenContext(ctx context.Context) (*ApiAuthorizerTokenResponse, error) {
 	// 优先从缓存中读取
 	authorizer_access_tokenn_key := fmt.Sprintf("authorizer_access_tokenn_key_%s", _this.Context.AppID)
-	authorizer_access_tokenn := _this.Context.Cache.Get(authorizer_access_tokenn_key)
-
-	if authorizer_access_tokenn == nil {
-		fmt.Printf("%v\n", "令牌失效，重新获取接口凭据.")
-		_, err := _this.QueryAuthCode(_this.authorizationCode, _this.componentAccessToken)
+	queryAuth := &ApiQueryAuthResponse{}
+	if !cache.GetJSON(_this.Context.Cache, authorizer_access_tokenn_key, queryAuth) {
+		_this.Log(ctx, wxcontext.LevelWarn, "令牌失效，重新获取接口凭据")
+		_, err := _this.QueryAuthCodeContext(ctx, _this.authorizationCode, _this.componentAccessToken)
 		return nil, err
 	}
 
-	queryAuth := authorizer_access_tokenn.(*ApiQueryAuthResponse)
 	jsonData := ApiAuthorizerTokenRequest{
 		ComponentAppId:         _this.ComponentAppId,
 		AuthorizerAppId:        queryAuth.AuthorizationInfo.AuthorizerAppId,
 		AuthorizerRefreshToken: queryAuth.AuthorizationInfo.AuthorizerRefreshToken,
 	}
 
-	jsonString, err := json.Marshal(jsonData)
-
-	if err != nil {
-		return nil, err
-	}
-
-	result, err := module_common.HttpPostJson(refreshTokenURL+_this.componentAccessToken, string(jsonString))
+	result, err := _this.postJSON(ctx, fmt.Sprintf(refreshTokenURL, _this.componentAccessToken), jsonData)
 
 	if err != nil {
 		return nil, err
 	}
 
 	authToken := &ApiAuthorizerTokenResponse{}
-	err = json.Unmarshal([]byte(result), authToken)
+	err = json.Unmarshal(result, authToken)
 
 	if err != nil {
 		return nil, err
 	}
 
-	fmt.Printf("authorizer_access_tokenn:%v\n", authToken.AuthorizerAccessToken)
-	fmt.Printf("authorizer_refresh_token:%v\n", authToken.AuthorizerRefreshToken)
-	fmt.Printf("expires_in:%v\n", authToken.ExpiresIn)
+	_this.Log(ctx, wxcontext.LevelInfo, "刷新授权方接口调用凭据", "authorizer_access_token", authToken.AuthorizerAccessToken, "authorizer_refresh_token", authToken.AuthorizerRefreshToken, "expires_in", authToken.ExpiresIn)
 
 	// 写入缓存
 	// expires := authToken.ExpiresIn - 1500
@@ -316,3 +396,47 @@ func (_this *Component) RefreshAuthToken() (*ApiAuthorizerTokenResponse, error)
 
 	return authToken, nil
 }
+
+// 第三方平台对其所有API调用次数清零（每月10次）
+func (_this *Component) ClearQuota() error {
+	return _this.ClearQuotaContext(context.Background())
+}
+
+// 同 ClearQuota，支持 context
+func (_this *Component) ClearQuotaContext(ctx context.Context) error {
+	componentAccessToken, err := _this.GetComponentAccessTokenContext(ctx, _this.verifyTicket())
+
+	if err != nil {
+		return err
+	}
+
+	jsonData := map[string]string{
+		"component_appid": _this.ComponentAppId,
+	}
+
+	_, err = _this.postJSON(ctx, fmt.Sprintf(clearQuotaURL, componentAccessToken.ComponentAccessToken), jsonData)
+
+	if err != nil {
+		return err
+	}
+
+	return _this.ResetQuotaUsage()
+}
+
+// 发送请求并记录接口调用次数，errcode不为0时返回 *util.APIError
+func (_this *Component) postJSON(ctx context.Context, rawURL string, obj interface{}) ([]byte, error) {
+	result, err := _this.PostJSONContext(ctx, _this.ResolveURL(rawURL), obj)
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = util.CheckAPIError(rawURL, result)
+	_this.CountCall(ctx, rawURL, err)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
@@ -0,0 +1,152 @@
+package component_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/MrCHI/gowechat"
+	"github.com/MrCHI/gowechat/cache"
+	"github.com/MrCHI/gowechat/util"
+	"github.com/MrCHI/gowechat/wxcontext"
+)
+
+const (
+	componentAppID    = "wx1234567890abcdef"
+	componentAppToken = "component_token"
+	componentAppKey   = "abcdefghijklmnopqrstuvwxyz0123456789ABCDEFG"
+)
+
+//newServer 模拟 api_component_token，ticket不对时返回61006
+func newServer(ticket string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]string
+		json.NewDecoder(r.Body).Decode(&req)
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path != "/cgi-bin/component/api_component_token" || req["component_verify_ticket"] != ticket {
+			w.Write([]byte(`{"errcode":61006,"errmsg":"component ticket is invalid"}`))
+			return
+		}
+		w.Write([]byte(`{"component_access_token":"COMPONENT_TOKEN","expires_in":7200}`))
+	}))
+}
+
+func newConfig(srv *httptest.Server, c cache.Cache) wxcontext.Config {
+	return wxcontext.Config{
+		Cache:              c,
+		Endpoint:           wxcontext.AllEndpoints(srv.URL),
+		ComponentAppId:     componentAppID,
+		ComponentAppSecret: "component_secret",
+		ComponentAppToken:  componentAppToken,
+		ComponentAppKey:    componentAppKey,
+	}
+}
+
+//encryptNotify 和微信一样加密推送内容，返回body和url参数
+func encryptNotify(t *testing.T, ticket string) (body, nonce, msgSign string, timestamp int64) {
+	raw := fmt.Sprintf("<xml><AppId><![CDATA[%s]]></AppId><CreateTime>%d</CreateTime>"+
+		"<InfoType><![CDATA[component_verify_ticket]]></InfoType>"+
+		"<ComponentVerifyTicket><![CDATA[%s]]></ComponentVerifyTicket></xml>", componentAppID, time.Now().Unix(), ticket)
+	encrypted, err := util.EncryptMsg([]byte(util.RandomStr(16)), []byte(raw), componentAppID, componentAppKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	timestamp = time.Now().Unix()
+	nonce = util.RandomStr(10)
+	msgSign = util.Signature(componentAppToken, strconv.FormatInt(timestamp, 10), nonce, string(encrypted))
+	body = fmt.Sprintf("<xml><AppId><![CDATA[%s]]></AppId><Encrypt><![CDATA[%s]]></Encrypt></xml>", componentAppID, encrypted)
+	return
+}
+
+func TestHandlerCallBack(t *testing.T) {
+	srv := newServer("TICKET")
+	defer srv.Close()
+	shared := cache.NewMemory(0)
+
+	op, err := gowechat.NewWechat(newConfig(srv, shared)).GetOpenPlatform()
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, nonce, msgSign, timestamp := encryptNotify(t, "TICKET")
+
+	if _, err := op.GetComponent().HandlerCallBack(body, nonce, "aes", "bad"+msgSign, timestamp); err == nil {
+		t.Error("expected signature error")
+	}
+	notify, err := op.GetComponent().HandlerCallBack(body, nonce, "aes", msgSign, timestamp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if notify.ComponentVerifyTicket != "TICKET" {
+		t.Errorf("unexpected ticket %q", notify.ComponentVerifyTicket)
+	}
+
+	//另一个实例通过缓存拿到ticket
+	other, _ := gowechat.NewWechat(newConfig(srv, shared)).GetOpenPlatform()
+	task := other.GetComponent().RefreshTask()
+	if _, err := task.Refresh(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestComponentTokenErrCode(t *testing.T) {
+	srv := newServer("TICKET")
+	defer srv.Close()
+	op, err := gowechat.NewWechat(newConfig(srv, cache.NewMemory(0))).GetOpenPlatform()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = op.GetComponent().GetComponentAccessToken("EXPIRED")
+	if util.ErrCodeOf(err) != 61006 {
+		t.Errorf("expected errcode 61006, got %v", err)
+	}
+}
+
+//ticketDropCache 不保存ticket，模拟缓存丢失时使用内存中的ticket
+type ticketDropCache struct {
+	*cache.Memory
+}
+
+func (c ticketDropCache) Put(key string, val interface{}, timeout time.Duration) error {
+	if strings.HasPrefix(key, "component_verify_ticket_") {
+		return nil
+	}
+	return c.Memory.Put(key, val, timeout)
+}
+
+func TestVerifyTicketConcurrent(t *testing.T) {
+	srv := newServer("TICKET")
+	defer srv.Close()
+	op, err := gowechat.NewWechat(newConfig(srv, ticketDropCache{cache.NewMemory(0)})).GetOpenPlatform()
+	if err != nil {
+		t.Fatal(err)
+	}
+	component := op.GetComponent()
+	task := component.RefreshTask()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			body, nonce, msgSign, timestamp := encryptNotify(t, "TICKET")
+			if _, err := component.HandlerCallBack(body, nonce, "aes", msgSign, timestamp); err != nil {
+				t.Error(err)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			task.Refresh(context.Background())
+		}()
+	}
+	wg.Wait()
+	if _, err := task.Refresh(context.Background()); err != nil {
+		t.Errorf("ticket from the push should be used, got %v", err)
+	}
+}
@@ -1,6 +1,7 @@
 package oauth
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/url"
@@ -32,15 +33,13 @@ func NewOauth(context *wxcontext.Context) *Oauth {
 // 代公众号发起网页授权
 func (oauth *Oauth) GetRedirectURL(redirectURI, serviceAppId, scope, state, componentAppId string) string {
 	urlStr := url.QueryEscape(redirectURI)
-	return fmt.Sprintf(redirectOauthURL, serviceAppId, urlStr, "code", scope, state, componentAppId)
+	return oauth.ResolveURL(fmt.Sprintf(redirectOauthURL, serviceAppId, urlStr, "code", scope, state, componentAppId))
 }
 
 //  代公众号发起网页授权，微信服务器回调
 func (oauth *Oauth) AuthCallBack(appid, state, code string) {
 
-	fmt.Printf("appid:%v\n", appid)
-	fmt.Printf("state:%v\n", state)
-	fmt.Printf("code:%v\n", code)
+	oauth.Log(context.Background(), wxcontext.LevelDebug, "网页授权回调", "authorizer_appid", appid, "state", state, "code", code)
 
 	// 保存状态
 	webCode := code
@@ -61,9 +60,14 @@ type ResAccessToken struct {
 
 // 通过code换取access_token
 func (oauth *Oauth) GetUserAccessToken(code string) (result ResAccessToken, err error) {
-	urlStr := fmt.Sprintf(accessTokenURL, oauth.AppID, oauth.AppSecret, code)
+	return oauth.GetUserAccessTokenContext(context.Background(), code)
+}
+
+//GetUserAccessTokenContext 同 GetUserAccessToken，支持 context
+func (oauth *Oauth) GetUserAccessTokenContext(ctx context.Context, code string) (result ResAccessToken, err error) {
+	urlStr := oauth.ResolveURL(fmt.Sprintf(accessTokenURL, oauth.AppID, oauth.AppSecret, code))
 	var response []byte
-	response, err = util.HTTPGet(urlStr)
+	response, err = oauth.HTTPGetContext(ctx, urlStr)
 	if err != nil {
 		return
 	}
@@ -72,7 +76,7 @@ func (oauth *Oauth) GetUserAccessToken(code string) (result ResAccessToken, err
 		return
 	}
 	if result.ErrCode != 0 {
-		err = fmt.Errorf("GetUserAccessToken error : errcode=%v , errmsg=%v", result.ErrCode, result.ErrMsg)
+		err = util.NewAPIError(accessTokenURL, result.ErrCode, result.ErrMsg)
 		return
 	}
 	return
@@ -80,14 +84,19 @@ func (oauth *Oauth) GetUserAccessToken(code string) (result ResAccessToken, err
 
 // 刷新access_token
 func (oauth *Oauth) RefreshAccessToken(serviceAppId, componentAppId, componentAccessToken, refreshToken string) (result ResAccessToken, err error) {
-	urlStr := fmt.Sprintf(refreshAccessTokenURL, "appid="+serviceAppId+
+	return oauth.RefreshAccessTokenContext(context.Background(), serviceAppId, componentAppId, componentAccessToken, refreshToken)
+}
+
+//RefreshAccessTokenContext 同 RefreshAccessToken，支持 context
+func (oauth *Oauth) RefreshAccessTokenContext(ctx context.Context, serviceAppId, componentAppId, componentAccessToken, refreshToken string) (result ResAccessToken, err error) {
+	urlStr := oauth.ResolveURL(fmt.Sprintf(refreshAccessTokenURL, "appid="+serviceAppId+
 		"&grant_type="+"authorization_code"+
 		"&component_appid="+componentAppId+
 		"&component_access_token="+componentAccessToken+
-		"&refresh_token="+refreshToken)
+		"&refresh_token="+refreshToken))
 
 	var response []byte
-	response, err = util.HTTPGet(urlStr)
+	response, err = oauth.HTTPGetContext(ctx, urlStr)
 	if err != nil {
 		return
 	}
@@ -96,7 +105,7 @@ func (oauth *Oauth) RefreshAccessToken(serviceAppId, componentAppId, componentAc
 		return
 	}
 	if result.ErrCode != 0 {
-		err = fmt.Errorf("GetUserAccessToken error : errcode=%v , errmsg=%v", result.ErrCode, result.ErrMsg)
+		err = util.NewAPIError(refreshAccessTokenURL, result.ErrCode, result.ErrMsg)
 		return
 	}
 	return
@@ -104,9 +113,14 @@ func (oauth *Oauth) RefreshAccessToken(serviceAppId, componentAppId, componentAc
 
 // 检验access_token是否有效
 func (oauth *Oauth) CheckAccessToken(accessToken, openID string) (b bool, err error) {
-	urlStr := fmt.Sprintf(checkAccessTokenURL, accessToken, openID)
+	return oauth.CheckAccessTokenContext(context.Background(), accessToken, openID)
+}
+
+//CheckAccessTokenContext 同 CheckAccessToken，支持 context
+func (oauth *Oauth) CheckAccessTokenContext(ctx context.Context, accessToken, openID string) (b bool, err error) {
+	urlStr := oauth.ResolveURL(fmt.Sprintf(checkAccessTokenURL, accessToken, openID))
 	var response []byte
-	response, err = util.HTTPGet(urlStr)
+	response, err = oauth.HTTPGetContext(ctx, urlStr)
 	if err != nil {
 		return
 	}
@@ -140,9 +154,14 @@ type UserInfo struct {
 
 // GetUserInfo 如果scope为 snsapi_userinfo 则可以通过此方法获取到用户基本信息
 func (oauth *Oauth) GetUserInfo(accessToken, openID string) (result UserInfo, err error) {
-	urlStr := fmt.Sprintf(userInfoURL, accessToken, openID)
+	return oauth.GetUserInfoContext(context.Background(), accessToken, openID)
+}
+
+//GetUserInfoContext 同 GetUserInfo，支持 context
+func (oauth *Oauth) GetUserInfoContext(ctx context.Context, accessToken, openID string) (result UserInfo, err error) {
+	urlStr := oauth.ResolveURL(fmt.Sprintf(userInfoURL, accessToken, openID))
 	var response []byte
-	response, err = util.HTTPGet(urlStr)
+	response, err = oauth.HTTPGetContext(ctx, urlStr)
 	if err != nil {
 		return
 	}
@@ -151,7 +170,7 @@ func (oauth *Oauth) GetUserInfo(accessToken, openID string) (result UserInfo, er
 		return
 	}
 	if result.ErrCode != 0 {
-		err = fmt.Errorf("GetUserInfo error : errcode=%v , errmsg=%v", result.ErrCode, result.ErrMsg)
+		err = util.NewAPIError(userInfoURL, result.ErrCode, result.ErrMsg)
 		return
 	}
 	return
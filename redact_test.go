@@ -0,0 +1,81 @@
+package gowechat
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/MrCHI/gowechat/cache"
+	"github.com/MrCHI/gowechat/metrics"
+	"github.com/MrCHI/gowechat/wxcontext"
+)
+
+//TestNoSecretLeaks 请求失败时，错误、日志和统计中都不能出现AppSecret和access_token
+func TestNoSecretLeaks(t *testing.T) {
+	const secret = "appsecret0123456789abcdef"
+	const token = "accesstoken0123456789abcdef"
+
+	var tokenOK bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/cgi-bin/token" && tokenOK {
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"access_token":"` + token + `","expires_in":7200}`))
+			return
+		}
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+
+	var logs bytes.Buffer
+	collector := metrics.NewCollector()
+	config := wxcontext.Config{
+		AppID:        "wx1111111111111111",
+		Cache:        cache.NewMemory(0),
+		AppSecret:    secret,
+		Token:        "token",
+		Endpoint:     wxcontext.AllEndpoints(srv.URL),
+		Logger:       wxcontext.NewStdLogger(log.New(&logs, "", 0), wxcontext.LevelDebug),
+		Interceptors: []wxcontext.Interceptor{collector},
+	}
+	mp, err := NewWechat(config).MpMgr()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var errs []string
+	if _, err := mp.GetAccessToken(); err != nil {
+		errs = append(errs, err.Error())
+	} else {
+		t.Error("expected token request to fail")
+	}
+	tokenOK = true
+	if _, err := mp.GetMenu().GetMenu(); err != nil {
+		errs = append(errs, err.Error())
+	} else {
+		t.Error("expected menu request to fail")
+	}
+	srv.Close()
+	if _, err := mp.GetMenu().GetMenu(); err != nil {
+		errs = append(errs, err.Error())
+	} else {
+		t.Error("expected menu request to fail")
+	}
+
+	var metricsOut bytes.Buffer
+	collector.WriteTo(&metricsOut)
+	outputs := map[string]string{
+		"errors":  strings.Join(errs, "\n"),
+		"logs":    logs.String(),
+		"metrics": metricsOut.String(),
+	}
+	for name, out := range outputs {
+		for _, s := range []string{secret, token} {
+			if strings.Contains(out, s) {
+				t.Errorf("%s leaked in %s:\n%s", s, name, out)
+			}
+		}
+	}
+}
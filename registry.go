@@ -0,0 +1,178 @@
+package gowechat
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/MrCHI/gowechat/wxcontext"
+)
+
+//MaxResolveBodySize ResolveRequest读取消息体的上限，微信推送的消息远小于该值
+const MaxResolveBodySize = 1 << 20
+
+//Registry 多账号管理，按AppID保存配置，第一次使用时才创建对应的Wechat
+//
+//同一个AppID的token锁在进程内共享（见 wxcontext.SharedTokenLock），没有设置Cache的账号共用默认的内存缓存
+type Registry struct {
+	mu       sync.RWMutex
+	accounts map[string]*registryEntry
+	//originalIDs 原始ID => AppID
+	originalIDs map[string]string
+}
+
+type registryEntry struct {
+	cfg  wxcontext.Config
+	once sync.Once
+	wc   *Wechat
+}
+
+func (e *registryEntry) wechat() *Wechat {
+	e.once.Do(func() {
+		e.wc = NewWechat(e.cfg)
+	})
+	return e.wc
+}
+
+//NewRegistry 实例化
+func NewRegistry() *Registry {
+	return &Registry{
+		accounts:    make(map[string]*registryEntry),
+		originalIDs: make(map[string]string),
+	}
+}
+
+//Register 添加账号，AppID不能为空也不能重复
+func (r *Registry) Register(cfg wxcontext.Config) error {
+	if cfg.AppID == "" {
+		return fmt.Errorf("%s", "配置中没有AppID")
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.accounts[cfg.AppID]; ok {
+		return fmt.Errorf("AppID %s 已经注册", cfg.AppID)
+	}
+	if cfg.OriginalID != "" {
+		if appID, ok := r.originalIDs[cfg.OriginalID]; ok {
+			return fmt.Errorf("OriginalID %s 已经被 %s 使用", cfg.OriginalID, appID)
+		}
+		r.originalIDs[cfg.OriginalID] = cfg.AppID
+	}
+	r.accounts[cfg.AppID] = &registryEntry{cfg: cfg}
+	return nil
+}
+
+//Remove 删除账号，已经取得的Wechat仍然可以使用
+func (r *Registry) Remove(appID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if e, ok := r.accounts[appID]; ok {
+		delete(r.originalIDs, e.cfg.OriginalID)
+		delete(r.accounts, appID)
+	}
+}
+
+//AppIDs 所有已注册的AppID，按字典序
+func (r *Registry) AppIDs() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	appIDs := make([]string, 0, len(r.accounts))
+	for appID := range r.accounts {
+		appIDs = append(appIDs, appID)
+	}
+	sort.Strings(appIDs)
+	return appIDs
+}
+
+//Get 按AppID获取
+func (r *Registry) Get(appID string) (*Wechat, error) {
+	r.mu.RLock()
+	e, ok := r.accounts[appID]
+	r.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("AppID %s 没有注册", appID)
+	}
+	return e.wechat(), nil
+}
+
+//GetByOriginalID 按公众号原始ID获取，即消息中的ToUserName
+func (r *Registry) GetByOriginalID(originalID string) (*Wechat, error) {
+	r.mu.RLock()
+	appID, ok := r.originalIDs[originalID]
+	r.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("OriginalID %s 没有注册", originalID)
+	}
+	return r.Get(appID)
+}
+
+//MpMgr 公众平台
+func (r *Registry) MpMgr(appID string) (*MpMgr, error) {
+	wc, err := r.Get(appID)
+	if err != nil {
+		return nil, err
+	}
+	return wc.MpMgr()
+}
+
+//MchMgr 商户平台
+func (r *Registry) MchMgr(appID string) (*MchMgr, error) {
+	wc, err := r.Get(appID)
+	if err != nil {
+		return nil, err
+	}
+	return wc.MchMgr()
+}
+
+//GetOpenPlatform 开放平台
+func (r *Registry) GetOpenPlatform(appID string) (*OpenPlatformManage, error) {
+	wc, err := r.Get(appID)
+	if err != nil {
+		return nil, err
+	}
+	return wc.GetOpenPlatform()
+}
+
+//ResolveRequest 根据微信推送的请求找到账号
+//
+//依次尝试：URL路径中的某一段等于已注册的AppID（例如 /wechat/{appid}），
+//消息体中的ToUserName等于已注册的OriginalID。读取消息体后会重新放回req.Body，
+//消息体超过 MaxResolveBodySize 时返回错误
+func (r *Registry) ResolveRequest(req *http.Request) (*Wechat, error) {
+	for _, seg := range strings.Split(req.URL.Path, "/") {
+		if seg == "" {
+			continue
+		}
+		if wc, err := r.Get(seg); err == nil {
+			return wc, nil
+		}
+	}
+
+	if req.Body == nil {
+		return nil, fmt.Errorf("%s", "请求中没有找到已注册的账号")
+	}
+	//多读一个字节用于判断是否超过上限
+	body, err := ioutil.ReadAll(io.LimitReader(req.Body, MaxResolveBodySize+1))
+	req.Body.Close()
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if len(body) > MaxResolveBodySize {
+		return nil, fmt.Errorf("消息体超过%d字节", MaxResolveBodySize)
+	}
+	//加密模式下ToUserName也是明文
+	var msg struct {
+		ToUserName string
+	}
+	if err = xml.Unmarshal(body, &msg); err != nil || msg.ToUserName == "" {
+		return nil, fmt.Errorf("%s", "请求中没有找到已注册的账号")
+	}
+	return r.GetByOriginalID(msg.ToUserName)
+}
@@ -0,0 +1,80 @@
+package gowechat
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/MrCHI/gowechat/wxcontext"
+)
+
+func TestRegistry(t *testing.T) {
+	r := NewRegistry()
+	for _, cfg := range []wxcontext.Config{
+		{AppID: "wx_b", OriginalID: "gh_b"},
+		{AppID: "wx_a", OriginalID: "gh_a"},
+	} {
+		if err := r.Register(cfg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if r.Register(wxcontext.Config{AppID: "wx_a"}) == nil || r.Register(wxcontext.Config{AppID: "wx_c", OriginalID: "gh_a"}) == nil ||
+		r.Register(wxcontext.Config{}) == nil {
+		t.Error("duplicate or empty ids should be rejected")
+	}
+	if got := r.AppIDs(); !reflect.DeepEqual(got, []string{"wx_a", "wx_b"}) {
+		t.Errorf("AppIDs = %v", got)
+	}
+
+	a1, _ := r.Get("wx_a")
+	a2, _ := r.GetByOriginalID("gh_a")
+	if a1 == nil || a1 != a2 {
+		t.Error("the same account should return the same Wechat")
+	}
+	//同一个AppID的Wechat共用token锁
+	if NewWechat(wxcontext.Config{AppID: "wx_a"}).Context.GetJsAPITicketLocker() != a1.Context.GetJsAPITicketLocker() {
+		t.Error("token locks should be shared per AppID")
+	}
+
+	r.Remove("wx_a")
+	if _, err := r.GetByOriginalID("gh_a"); err == nil {
+		t.Error("removed account should not be found")
+	}
+	if err := r.Register(wxcontext.Config{AppID: "wx_c", OriginalID: "gh_a"}); err != nil {
+		t.Errorf("OriginalID should be free after Remove, got %v", err)
+	}
+}
+
+func TestResolveRequest(t *testing.T) {
+	r := NewRegistry()
+	r.Register(wxcontext.Config{AppID: "wx_a", OriginalID: "gh_a"})
+	want, _ := r.Get("wx_a")
+
+	req := httptest.NewRequest(http.MethodPost, "/wechat/wx_a?signature=x", nil)
+	if wc, err := r.ResolveRequest(req); err != nil || wc != want {
+		t.Errorf("resolve by path: %v", err)
+	}
+
+	body := "<xml><ToUserName><![CDATA[gh_a]]></ToUserName><Encrypt><![CDATA[x]]></Encrypt></xml>"
+	req = httptest.NewRequest(http.MethodPost, "/wechat", strings.NewReader(body))
+	if wc, err := r.ResolveRequest(req); err != nil || wc != want {
+		t.Errorf("resolve by ToUserName: %v", err)
+	}
+	if rest, _ := ioutil.ReadAll(req.Body); string(rest) != body {
+		t.Errorf("body should be put back, got %q", rest)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/wechat", strings.NewReader("<xml><ToUserName>gh_x</ToUserName></xml>"))
+	if _, err := r.ResolveRequest(req); err == nil {
+		t.Error("unknown account should fail")
+	}
+
+	large := "<xml><ToUserName>gh_a</ToUserName><Content>" + strings.Repeat("x", MaxResolveBodySize) + "</Content></xml>"
+	req = httptest.NewRequest(http.MethodPost, "/wechat", strings.NewReader(large))
+	if _, err := r.ResolveRequest(req); err == nil {
+		t.Error("oversized body should be rejected")
+	}
+}
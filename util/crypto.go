@@ -92,6 +92,12 @@ func DecryptMsg(appID, encryptedMsg, aesKey string) (random, rawMsgXMLBytes []by
 	return
 }
 
+//CheckAESKey 检查EncodingAESKey是否有效（43位，base64解码后32字节）
+func CheckAESKey(encodedAESKey string) error {
+	_, err := aesKeyDecode(encodedAESKey)
+	return err
+}
+
 func aesKeyDecode(encodedAESKey string) (key []byte, err error) {
 	if len(encodedAESKey) != 43 {
 		err = fmt.Errorf("the length of encodedAESKey must be equal to 43")
@@ -0,0 +1,83 @@
+package util
+
+import "errors"
+
+//微信全局返回码，完整列表见官方文档"全局返回码说明"
+const (
+	ErrCodeSystemBusy            int64 = -1    //系统繁忙，此时请开发者稍候再试
+	ErrCodeInvalidCredential     int64 = 40001 //AppSecret错误或者access_token无效
+	ErrCodeInvalidGrantType      int64 = 40002 //不合法的凭证类型
+	ErrCodeInvalidOpenID         int64 = 40003 //不合法的OpenID
+	ErrCodeInvalidMediaID        int64 = 40007 //不合法的媒体文件id
+	ErrCodeInvalidAppID          int64 = 40013 //不合法的AppID
+	ErrCodeInvalidAccessToken    int64 = 40014 //不合法的access_token
+	ErrCodeInvalidCode           int64 = 40029 //不合法的oauth_code
+	ErrCodeInvalidIP             int64 = 40164 //调用接口的IP地址不在白名单中
+	ErrCodeAccessTokenMissing    int64 = 41001 //缺少access_token参数
+	ErrCodeAccessTokenExpired    int64 = 42001 //access_token超时
+	ErrCodeRequireSubscribe      int64 = 43004 //需要接收者关注
+	ErrCodeAPIDailyQuotaExceeded int64 = 45009 //接口调用超过限制
+	ErrCodeAPIFreqOutOfLimit     int64 = 45011 //API调用太频繁，请稍候再试
+	ErrCodeResponseOutOfTime     int64 = 45015 //回复时间超过限制
+	ErrCodeOutOfResponseCount    int64 = 45047 //客服接口下行条数超过上限
+	ErrCodeAPIUnauthorized       int64 = 48001 //api功能未授权
+	ErrCodeUserLimited           int64 = 50002 //用户受限
+)
+
+var errCodeText = map[int64]string{
+	ErrCodeSystemBusy:            "系统繁忙",
+	ErrCodeInvalidCredential:     "AppSecret错误或者access_token无效",
+	ErrCodeInvalidGrantType:      "不合法的凭证类型",
+	ErrCodeInvalidOpenID:         "不合法的OpenID",
+	ErrCodeInvalidMediaID:        "不合法的媒体文件id",
+	ErrCodeInvalidAppID:          "不合法的AppID",
+	ErrCodeInvalidAccessToken:    "不合法的access_token",
+	ErrCodeInvalidCode:           "不合法的oauth_code",
+	ErrCodeInvalidIP:             "调用接口的IP地址不在白名单中",
+	ErrCodeAccessTokenMissing:    "缺少access_token参数",
+	ErrCodeAccessTokenExpired:    "access_token超时",
+	ErrCodeRequireSubscribe:      "需要接收者关注",
+	ErrCodeAPIDailyQuotaExceeded: "接口调用超过每日限制",
+	ErrCodeAPIFreqOutOfLimit:     "接口调用太频繁",
+	ErrCodeResponseOutOfTime:     "回复时间超过限制",
+	ErrCodeOutOfResponseCount:    "客服接口下行条数超过上限",
+	ErrCodeAPIUnauthorized:       "api功能未授权",
+	ErrCodeUserLimited:           "用户受限",
+}
+
+//ErrCodeText 返回码的中文说明，未收录的返回空字符串
+func ErrCodeText(code int64) string {
+	return errCodeText[code]
+}
+
+//ErrCodeOf 取出err中的errcode，不是 *APIError 时返回0
+func ErrCodeOf(err error) int64 {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.ErrCode
+	}
+	return 0
+}
+
+//IsTokenInvalid access_token无效或过期，刷新access_token后可以重试
+func IsTokenInvalid(err error) bool {
+	switch ErrCodeOf(err) {
+	case ErrCodeInvalidCredential, ErrCodeInvalidAccessToken, ErrCodeAccessTokenExpired:
+		return true
+	}
+	return false
+}
+
+//IsRateLimited 触发了接口的频率或每日调用次数限制
+func IsRateLimited(err error) bool {
+	switch ErrCodeOf(err) {
+	case ErrCodeAPIDailyQuotaExceeded, ErrCodeAPIFreqOutOfLimit, ErrCodeOutOfResponseCount:
+		return true
+	}
+	return false
+}
+
+//IsRetryable 系统繁忙或access_token失效，稍后（或刷新token后）重试可能成功
+func IsRetryable(err error) bool {
+	return ErrCodeOf(err) == ErrCodeSystemBusy || IsTokenInvalid(err)
+}
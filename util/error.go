@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strings"
 )
 
 //ErrUnmarshall err when unmarshall
@@ -24,15 +25,48 @@ func (e *CommonError) Error() string {
 	return e.ErrMsg
 }
 
+//APIError 微信接口返回的错误(errcode不为0)，可以用 errors.As 取出
+type APIError struct {
+	ErrCode  int64
+	ErrMsg   string
+	Rid      string //微信的请求ID，向微信反馈问题时使用
+	Endpoint string //出错的接口地址，不含参数
+}
+
+//NewAPIError 实例化，endpoint 中的参数会被去掉，rid 从 errmsg 中解析
+func NewAPIError(endpoint string, code int64, msg string) *APIError {
+	if i := strings.IndexAny(endpoint, "?#"); i >= 0 {
+		endpoint = endpoint[:i]
+	}
+	e := &APIError{ErrCode: code, ErrMsg: msg, Endpoint: endpoint}
+	if i := strings.LastIndex(msg, "rid:"); i >= 0 {
+		e.Rid = strings.TrimSpace(msg[i+len("rid:"):])
+	}
+	return e
+}
+
+func (e *APIError) Error() string {
+	msg := RedactString(e.ErrMsg)
+	if e.Endpoint == "" {
+		return fmt.Sprintf("Error , errcode=%d , errmsg=%s", e.ErrCode, msg)
+	}
+	return fmt.Sprintf("%s error , errcode=%d , errmsg=%s", e.Endpoint, e.ErrCode, msg)
+}
+
 //CheckCommonError check CommonError
 func CheckCommonError(jsonData []byte) error {
+	return CheckAPIError("", jsonData)
+}
+
+//CheckAPIError 检查接口返回的errcode，不为0时返回 *APIError
+func CheckAPIError(endpoint string, jsonData []byte) error {
 	var errmsg CommonError
 	if err := json.Unmarshal(jsonData, &errmsg); err != nil {
 		return ErrUnmarshall
 	}
 
 	if errmsg.ErrCode != 0 {
-		return fmt.Errorf("Error , errcode=%d , errmsg=%s", errmsg.ErrCode, errmsg.ErrMsg)
+		return NewAPIError(endpoint, errmsg.ErrCode, errmsg.ErrMsg)
 	}
 
 	return nil
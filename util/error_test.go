@@ -0,0 +1,36 @@
+package util
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestCheckAPIError(t *testing.T) {
+	body := []byte(`{"errcode":40001,"errmsg":"invalid credential, access_token is invalid or not latest rid: 5f3a1b2c-0d1e2f3a"}`)
+	err := CheckAPIError("https://api.weixin.qq.com/cgi-bin/menu/get?access_token=TOKEN", body)
+
+	var apiErr *APIError
+	if !errors.As(fmt.Errorf("wrapped: %w", err), &apiErr) {
+		t.Fatalf("want *APIError, got %T", err)
+	}
+	if apiErr.Endpoint != "https://api.weixin.qq.com/cgi-bin/menu/get" {
+		t.Errorf("endpoint not stripped: %s", apiErr.Endpoint)
+	}
+	if apiErr.Rid != "5f3a1b2c-0d1e2f3a" {
+		t.Errorf("rid = %q", apiErr.Rid)
+	}
+	if !IsTokenInvalid(err) || !IsRetryable(err) || IsRateLimited(err) {
+		t.Error("40001 classified wrongly")
+	}
+
+	if err := CheckAPIError("", []byte(`{"errcode":45009,"errmsg":"reach max api daily quota limit"}`)); !IsRateLimited(err) || IsRetryable(err) {
+		t.Error("45009 classified wrongly")
+	}
+	if err := CheckAPIError("", []byte(`{"errcode":0,"errmsg":"ok"}`)); err != nil {
+		t.Errorf("errcode 0 should be nil, got %v", err)
+	}
+	if err := CheckAPIError("", []byte(`not json`)); err != ErrUnmarshall {
+		t.Errorf("want ErrUnmarshall, got %v", err)
+	}
+}
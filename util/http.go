@@ -2,34 +2,62 @@ package util
 
 import (
 	"bytes"
+	"context"
 	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
-	"mime/multipart"
 	"net"
 	"net/http"
-	"os"
 	"time"
 )
 
+//Doer 发送HTTP请求，*http.Client 实现了此接口
+type Doer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
 //HTTPGet get 请求
 func HTTPGet(uri string) ([]byte, error) {
-	response, err := http.Get(uri)
+	return HTTPGetWithClient(http.DefaultClient, uri)
+}
+
+//HTTPGetWithClient 使用指定的client发送get请求
+func HTTPGetWithClient(client Doer, uri string) ([]byte, error) {
+	return HTTPGetContext(context.Background(), client, uri)
+}
+
+//HTTPGetContext 使用指定的client发送get请求, ctx取消时请求随之取消
+func HTTPGetContext(ctx context.Context, client Doer, uri string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri, nil)
 	if err != nil {
-		return nil, err
+		return nil, RedactError(err)
+	}
+	response, err := client.Do(req)
+	if err != nil {
+		return nil, RedactError(err)
 	}
 
 	defer response.Body.Close()
 	if response.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("http get error : uri=%v , statusCode=%v", uri, response.StatusCode)
+		return nil, fmt.Errorf("http get error : uri=%v , statusCode=%v", RedactURL(uri), response.StatusCode)
 	}
 	return ioutil.ReadAll(response.Body)
 }
 
 //PostJSON post json 数据请求
 func PostJSON(url string, obj interface{}) ([]byte, error) {
+	return PostJSONWithClient(http.DefaultClient, url, obj)
+}
+
+//PostJSONWithClient 使用指定的client发送json数据
+func PostJSONWithClient(client Doer, url string, obj interface{}) ([]byte, error) {
+	return PostJSONContext(context.Background(), client, url, obj)
+}
+
+//PostJSONContext 使用指定的client发送json数据, ctx取消时请求随之取消
+func PostJSONContext(ctx context.Context, client Doer, url string, obj interface{}) ([]byte, error) {
 	jsonData, err := json.Marshal(obj)
 	if err != nil {
 		return nil, err
@@ -39,21 +67,35 @@ func PostJSON(url string, obj interface{}) ([]byte, error) {
 	jsonData = bytes.Replace(jsonData, []byte("\\u003e"), []byte(">"), -1)
 	jsonData = bytes.Replace(jsonData, []byte("\\u0026"), []byte("&"), -1)
 
-	body := bytes.NewBuffer(jsonData)
-	response, err := http.Post(url, "application/json;charset=utf-8", body)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(jsonData))
 	if err != nil {
-		return nil, err
+		return nil, RedactError(err)
+	}
+	req.Header.Set("Content-Type", "application/json;charset=utf-8")
+	response, err := client.Do(req)
+	if err != nil {
+		return nil, RedactError(err)
 	}
 	defer response.Body.Close()
 
 	if response.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("http get error : uri=%v , statusCode=%v", url, response.StatusCode)
+		return nil, fmt.Errorf("http get error : uri=%v , statusCode=%v", RedactURL(url), response.StatusCode)
 	}
 	return ioutil.ReadAll(response.Body)
 }
 
 //PostFile 上传文件
 func PostFile(fieldname, filename, uri string) ([]byte, error) {
+	return PostFileWithClient(http.DefaultClient, fieldname, filename, uri)
+}
+
+//PostFileWithClient 使用指定的client上传文件
+func PostFileWithClient(client Doer, fieldname, filename, uri string) ([]byte, error) {
+	return PostFileContext(context.Background(), client, fieldname, filename, uri)
+}
+
+//PostFileContext 使用指定的client上传文件, ctx取消时请求随之取消
+func PostFileContext(ctx context.Context, client Doer, fieldname, filename, uri string) ([]byte, error) {
 	fields := []MultipartFormField{
 		{
 			IsFile:    true,
@@ -61,64 +103,59 @@ func PostFile(fieldname, filename, uri string) ([]byte, error) {
 			Filename:  filename,
 		},
 	}
-	return PostMultipartForm(fields, uri)
+	return PostMultipartFormContext(ctx, client, fields, uri)
 }
 
 //MultipartFormField 保存文件或其他字段信息
+//
+//IsFile为true时上传文件：Reader不为空时从Reader读取，否则打开Filename指定的文件
 type MultipartFormField struct {
 	IsFile    bool
 	Fieldname string
 	Value     []byte
 	Filename  string
+
+	Reader      io.Reader //文件内容，上传时边读边发，不会整个读入内存
+	ContentType string    //文件的Content-Type，为空时按Filename的扩展名判断
 }
 
 //PostMultipartForm 上传文件或其他多个字段
 func PostMultipartForm(fields []MultipartFormField, uri string) (respBody []byte, err error) {
-	bodyBuf := &bytes.Buffer{}
-	bodyWriter := multipart.NewWriter(bodyBuf)
-
-	for _, field := range fields {
-		if field.IsFile {
-			fileWriter, e := bodyWriter.CreateFormFile(field.Fieldname, field.Filename)
-			if e != nil {
-				err = fmt.Errorf("error writing to buffer , err=%v", e)
-				return
-			}
-
-			fh, e := os.Open(field.Filename)
-			if e != nil {
-				err = fmt.Errorf("error opening file , err=%v", e)
-				return
-			}
-			defer fh.Close()
-
-			if _, err = io.Copy(fileWriter, fh); err != nil {
-				return
-			}
-		} else {
-			partWriter, e := bodyWriter.CreateFormField(field.Fieldname)
-			if e != nil {
-				err = e
-				return
-			}
-			valueReader := bytes.NewReader(field.Value)
-			if _, err = io.Copy(partWriter, valueReader); err != nil {
-				return
-			}
-		}
-	}
-
-	contentType := bodyWriter.FormDataContentType()
-	bodyWriter.Close()
-
-	resp, e := http.Post(uri, contentType, bodyBuf)
+	return PostMultipartFormWithClient(http.DefaultClient, fields, uri)
+}
+
+//PostMultipartFormWithClient 使用指定的client上传文件或其他多个字段
+func PostMultipartFormWithClient(client Doer, fields []MultipartFormField, uri string) (respBody []byte, err error) {
+	return PostMultipartFormContext(context.Background(), client, fields, uri)
+}
+
+//PostMultipartFormContext 使用指定的client上传文件或其他多个字段, ctx取消时请求随之取消
+//
+//文件内容不会读入内存；所有文件的大小都能取得时（见 SizedReader）设置Content-Length，否则使用chunked编码
+func PostMultipartFormContext(ctx context.Context, client Doer, fields []MultipartFormField, uri string) (respBody []byte, err error) {
+	body, err := newMultipartBody(fields)
+	if err != nil {
+		return
+	}
+	defer body.Close()
+
+	req, e := http.NewRequestWithContext(ctx, http.MethodPost, uri, body)
 	if e != nil {
-		err = e
+		err = RedactError(e)
+		return
+	}
+	req.Header.Set("Content-Type", body.contentType)
+	if body.size >= 0 {
+		req.ContentLength = body.size
+	}
+	resp, e := client.Do(req)
+	if e != nil {
+		err = RedactError(e)
 		return
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode != http.StatusOK {
-		return nil, err
+		return nil, fmt.Errorf("http post error : uri=%v , statusCode=%v", RedactURL(uri), resp.StatusCode)
 	}
 	respBody, err = ioutil.ReadAll(resp.Body)
 	return
@@ -128,8 +165,7 @@ func PostMultipartForm(fields []MultipartFormField, uri string) (respBody []byte
 func NewTLSHttpClientFromContent(certContent, keyContent string) (httpClient *http.Client, err error) {
 	cert, err := tls.X509KeyPair([]byte(certContent), []byte(keyContent))
 	if err != nil {
-		fmt.Print("can not init cert...")
-		return nil, err
+		return nil, fmt.Errorf("can not init cert: %v", err)
 	}
 	tlsConfig := &tls.Config{
 		Certificates: []tls.Certificate{cert},
@@ -154,8 +190,7 @@ func NewTLSHttpClientFromContent(certContent, keyContent string) (httpClient *ht
 func NewTLSHttpClient(certFile, keyFile string) (httpClient *http.Client, err error) {
 	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
 	if err != nil {
-		fmt.Print("can not init cert...")
-		return nil, err
+		return nil, fmt.Errorf("can not init cert: %v", err)
 	}
 	tlsConfig := &tls.Config{
 		Certificates: []tls.Certificate{cert},
@@ -0,0 +1,140 @@
+package util
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+//SizedReader 为无法取得大小的Reader（例如网络流）指定大小，上传时就可以设置Content-Length
+func SizedReader(r io.Reader, size int64) io.Reader {
+	return &sizedReader{Reader: io.LimitReader(r, size), size: size}
+}
+
+type sizedReader struct {
+	io.Reader
+	size int64
+}
+
+//readerSize 返回还未读取的字节数，无法取得时返回-1
+func readerSize(r io.Reader) int64 {
+	switch v := r.(type) {
+	case *sizedReader:
+		return v.size
+	case interface{ Len() int }: //*bytes.Reader *bytes.Buffer *strings.Reader
+		return int64(v.Len())
+	case *os.File:
+		info, err := v.Stat()
+		if err != nil || !info.Mode().IsRegular() {
+			return -1
+		}
+		offset, err := v.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return -1
+		}
+		return info.Size() - offset
+	}
+	return -1
+}
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+//multipartBody 依次读取各部分的头和文件内容，文件在请求结束后关闭
+type multipartBody struct {
+	io.Reader
+	contentType string
+	size        int64
+	files       []*os.File
+}
+
+func (b *multipartBody) Close() error {
+	for _, f := range b.files {
+		f.Close()
+	}
+	return nil
+}
+
+func newMultipartBody(fields []MultipartFormField) (body *multipartBody, err error) {
+	var (
+		buf     bytes.Buffer
+		readers []io.Reader
+		size    int64
+	)
+	body = new(multipartBody)
+	defer func() {
+		if err != nil {
+			body.Close()
+		}
+	}()
+	w := multipart.NewWriter(&buf)
+	//flush 把已经写入buf的内容作为一段
+	flush := func() {
+		if size >= 0 {
+			size += int64(buf.Len())
+		}
+		readers = append(readers, bytes.NewReader(append([]byte(nil), buf.Bytes()...)))
+		buf.Reset()
+	}
+
+	for _, field := range fields {
+		if !field.IsFile {
+			var partWriter io.Writer
+			if partWriter, err = w.CreateFormField(field.Fieldname); err != nil {
+				return
+			}
+			partWriter.Write(field.Value)
+			continue
+		}
+
+		r := field.Reader
+		if r == nil {
+			var fh *os.File
+			if fh, err = os.Open(field.Filename); err != nil {
+				err = fmt.Errorf("error opening file , err=%v", err)
+				return
+			}
+			body.files = append(body.files, fh)
+			r = fh
+		}
+		contentType := field.ContentType
+		if contentType == "" {
+			contentType = mime.TypeByExtension(filepath.Ext(field.Filename))
+		}
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		h := make(textproto.MIMEHeader)
+		h.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`,
+			quoteEscaper.Replace(field.Fieldname), quoteEscaper.Replace(filepath.Base(field.Filename))))
+		h.Set("Content-Type", contentType)
+		if _, err = w.CreatePart(h); err != nil {
+			err = fmt.Errorf("error writing to buffer , err=%v", err)
+			return
+		}
+		flush()
+		readers = append(readers, r)
+		if n := readerSize(r); n >= 0 && size >= 0 {
+			size += n
+		} else {
+			size = -1
+		}
+	}
+	if err = w.Close(); err != nil {
+		return
+	}
+	if size >= 0 {
+		size += int64(buf.Len())
+	}
+	readers = append(readers, bytes.NewReader(buf.Bytes()))
+
+	body.Reader = io.MultiReader(readers...)
+	body.contentType = w.FormDataContentType()
+	body.size = size
+	return
+}
@@ -0,0 +1,53 @@
+package util
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"strings"
+	"testing"
+)
+
+func TestMultipartBody(t *testing.T) {
+	content := strings.Repeat("0123456789", 100)
+	cases := []struct {
+		name     string
+		reader   io.Reader
+		wantSize bool
+	}{
+		{"known size", strings.NewReader(content), true},
+		{"sized reader", SizedReader(ioutil.NopCloser(strings.NewReader(content)), int64(len(content))), true},
+		{"unknown size", ioutil.NopCloser(strings.NewReader(content)), false},
+	}
+	for _, c := range cases {
+		body, err := newMultipartBody([]MultipartFormField{
+			{IsFile: true, Fieldname: "media", Filename: "dir/a.jpg", Reader: c.reader},
+			{Fieldname: "description", Value: []byte(`{"title":"t"}`)},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := ioutil.ReadAll(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if (c.wantSize && body.size != int64(len(data))) || (!c.wantSize && body.size != -1) {
+			t.Errorf("%s: size %d, body %d bytes", c.name, body.size, len(data))
+		}
+
+		_, params, _ := mime.ParseMediaType(body.contentType)
+		form, err := multipart.NewReader(bytes.NewReader(data), params["boundary"]).ReadForm(1 << 20)
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		file := form.File["media"][0]
+		if file.Filename != "a.jpg" || file.Header.Get("Content-Type") != "image/jpeg" || file.Size != int64(len(content)) {
+			t.Errorf("%s: unexpected file %s %s %d", c.name, file.Filename, file.Header.Get("Content-Type"), file.Size)
+		}
+		if form.Value["description"][0] != `{"title":"t"}` {
+			t.Errorf("%s: unexpected description %v", c.name, form.Value)
+		}
+	}
+}
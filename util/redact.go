@@ -0,0 +1,153 @@
+package util
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+//SensitiveKeys 参数名、字段名中包含这些词（不区分大小写）时，它的值会被脱敏；
+//另外名为key或以key结尾的（例如 api_key、EncodingAESKey）也会脱敏，event_key除外
+var SensitiveKeys = []string{"secret", "token", "ticket", "sign", "password"}
+
+//keyExempt 以key结尾但不是密钥的字段，例如菜单事件的EventKey
+var keyExempt = map[string]bool{
+	"event_key": true,
+	"eventkey":  true,
+}
+
+//sensitiveCodes 一次性的授权码，值同样需要脱敏
+var sensitiveCodes = map[string]bool{
+	"code":               true,
+	"auth_code":          true,
+	"js_code":            true,
+	"pre_auth_code":      true,
+	"authorization_code": true,
+}
+
+//IsSensitiveKey 参数名是否为secret、access_token、sign、各种key等敏感信息
+func IsSensitiveKey(key string) bool {
+	key = strings.ToLower(key)
+	if sensitiveCodes[key] {
+		return true
+	}
+	for _, s := range SensitiveKeys {
+		if strings.Contains(key, s) {
+			return true
+		}
+	}
+	return strings.HasSuffix(key, "key") && !keyExempt[key]
+}
+
+//RedactValue 只保留前3个字符，方便排查时区分不同的值；6个字符以内全部隐藏
+func RedactValue(val string) string {
+	r := []rune(val)
+	if len(r) <= 6 {
+		return "***"
+	}
+	return string(r[:3]) + "***"
+}
+
+//RedactURL 隐藏url中敏感参数的值和用户密码，例如 access_token=abc***
+func RedactURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return RedactString(rawURL)
+	}
+	if _, ok := u.User.Password(); ok {
+		u.User = url.UserPassword(u.User.Username(), "***")
+	}
+	if u.RawQuery != "" {
+		u.RawQuery = redactQuery(u.RawQuery)
+	}
+	//UserPassword会把*转义
+	return strings.Replace(u.String(), ":%2A%2A%2A@", ":***@", 1)
+}
+
+func redactQuery(query string) string {
+	params := strings.Split(query, "&")
+	for i, param := range params {
+		kv := strings.SplitN(param, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		key, err := url.QueryUnescape(kv[0])
+		if err != nil || !IsSensitiveKey(key) {
+			continue
+		}
+		val, err := url.QueryUnescape(kv[1])
+		if err != nil {
+			val = kv[1]
+		}
+		params[i] = kv[0] + "=" + RedactValue(val)
+	}
+	return strings.Join(params, "&")
+}
+
+var (
+	//key=value，例如错误信息中的url
+	redactParamRe = regexp.MustCompile(`([A-Za-z_]+)=([^&\s"'#,;]+)`)
+	//"key": "value"
+	redactJSONRe = regexp.MustCompile(`"([A-Za-z_]+)"\s*:\s*"([^"]*)"`)
+	//<key>value</key> 和 <key><![CDATA[value]]></key>
+	redactXMLRe = regexp.MustCompile(`<([A-Za-z_]+)>(?:<!\[CDATA\[([^\]]*)\]\]>|([^<]*))</`)
+	//scheme://user:password@
+	redactUserRe = regexp.MustCompile(`(://[^/\s:@]+:)[^/\s@]+@`)
+)
+
+//RedactString 隐藏任意文本（错误信息、日志）中的敏感信息，识别 key=value、json 和 xml 三种形式
+func RedactString(s string) string {
+	s = redactParamRe.ReplaceAllStringFunc(s, func(m string) string {
+		sub := redactParamRe.FindStringSubmatch(m)
+		if !IsSensitiveKey(sub[1]) {
+			return m
+		}
+		return sub[1] + "=" + RedactValue(sub[2])
+	})
+	s = redactJSONRe.ReplaceAllStringFunc(s, func(m string) string {
+		sub := redactJSONRe.FindStringSubmatch(m)
+		if !IsSensitiveKey(sub[1]) {
+			return m
+		}
+		return `"` + sub[1] + `":"` + RedactValue(sub[2]) + `"`
+	})
+	s = redactXMLRe.ReplaceAllStringFunc(s, func(m string) string {
+		sub := redactXMLRe.FindStringSubmatch(m)
+		if !IsSensitiveKey(sub[1]) {
+			return m
+		}
+		return "<" + sub[1] + ">" + RedactValue(sub[2]+sub[3]) + "</"
+	})
+	return redactUserRe.ReplaceAllString(s, "${1}***@")
+}
+
+//redactedError 脱敏后的错误，Unwrap 仍然返回原始错误
+type redactedError struct {
+	msg string
+	err error
+}
+
+func (e *redactedError) Error() string {
+	return e.msg
+}
+
+func (e *redactedError) Unwrap() error {
+	return e.err
+}
+
+//RedactError 返回Error()中不含敏感信息的错误，没有需要隐藏的内容时原样返回。
+//*url.Error 仍然返回 *url.Error（只替换URL），其他错误可以用 errors.As/Is 取出原始错误
+func RedactError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if urlErr, ok := err.(*url.Error); ok {
+		return &url.Error{Op: urlErr.Op, URL: RedactURL(urlErr.URL), Err: RedactError(urlErr.Err)}
+	}
+	msg := err.Error()
+	redacted := RedactString(msg)
+	if redacted == msg {
+		return err
+	}
+	return &redactedError{msg: redacted, err: err}
+}
@@ -2,15 +2,21 @@
 package gowechat
 
 import (
+	stdcontext "context"
 	"fmt"
 	"sync"
 
+	"github.com/MrCHI/gowechat/cache"
+	"github.com/MrCHI/gowechat/mp/jssdk"
 	"github.com/MrCHI/gowechat/wxcontext"
-	"github.com/astaxie/beego/cache"
 )
 
 //memCache if wxcontext.Config no cache, this will give a default memory cache.
-var memCache cache.Cache
+var (
+	memCache     cache.Cache
+	memCacheOnce sync.Once
+	lockerWarned sync.Map
+)
 
 // Wechat struct
 type Wechat struct {
@@ -26,18 +32,37 @@ func NewWechat(cfg wxcontext.Config) *Wechat {
 
 func initContext(cfg *wxcontext.Config, context *wxcontext.Context) {
 	if cfg.Cache == nil {
-		if memCache == nil {
-			memCache, _ = cache.NewCache("memory", `{"interval":60}`)
-		}
+		memCacheOnce.Do(func() {
+			memCache = cache.NewMemory(cache.DefaultGCInterval)
+		})
 		cfg.Cache = memCache
 	}
 	context.Config = cfg
+	if _, ok := cfg.Cache.(cache.Locker); !ok {
+		//每种存储只提示一次
+		if _, warned := lockerWarned.LoadOrStore(fmt.Sprintf("%T", cfg.Cache), true); !warned {
+			context.Log(stdcontext.Background(), wxcontext.LevelWarn, "Cache没有实现cache.Locker，多个进程共用AppID时不会协调token的刷新",
+				"cache", fmt.Sprintf("%T", cfg.Cache))
+		}
+	}
 
-	context.SetAccessTokenLock(new(sync.RWMutex))
-	context.SetJsAPITicketLock(new(sync.RWMutex))
+	//同一个AppID的多个Wechat共用锁
+	context.SetAccessTokenLocker(wxcontext.SharedTokenLock("access_token_" + cfg.AppID))
+	context.SetJsAPITicketLocker(wxcontext.SharedTokenLock("jsapi_ticket_" + cfg.AppID))
 
 }
 
+//NewRefresher 创建后台刷新器，提前刷新access_token、jsapi_ticket，tasks为额外的任务
+//
+//开放平台的component_access_token需要收到推送的component_verify_ticket，
+//请传入处理推送的 Component 的 RefreshTask()。调用 Start 启动，程序退出前调用 Stop
+func (wc *Wechat) NewRefresher(tasks ...wxcontext.RefreshTask) *wxcontext.Refresher {
+	if wc.checkCfgBase() == nil {
+		tasks = append([]wxcontext.RefreshTask{wc.Context.AccessTokenRefreshTask(), jssdk.NewJs(wc.Context).RefreshTask()}, tasks...)
+	}
+	return wxcontext.NewRefresher(tasks...)
+}
+
 //MchMgr 商户平台
 func (wc *Wechat) MchMgr() (mch *MchMgr, err error) {
 	err = wc.checkCfgMch()
@@ -76,36 +101,14 @@ func (wc *Wechat) GetOpenPlatform() (op *OpenPlatformManage, err error) {
 
 //checkCfgBase 检查配置基本信息
 func (wc *Wechat) checkCfgBase() (err error) {
-	if wc.Context.AppID == "" {
-		return fmt.Errorf("%s", "配置中没有AppID")
-	}
-	if wc.Context.AppSecret == "" {
-		return fmt.Errorf("%s", "配置中没有AppSecret")
-	}
-	if wc.Context.Token == "" {
-		return fmt.Errorf("%s", "配置中没有Token")
-	}
-	return
+	return wc.Context.ValidateMp()
 }
 
 // 检查商务平台参数
 func (wc *Wechat) checkCfgMch() (err error) {
-	err = wc.checkCfgBase()
-	if err != nil {
+	if err = wc.Context.ValidateMch(); err != nil {
 		return
 	}
-	if wc.Context.MchID == "" {
-		return fmt.Errorf("%s", "配置中没有MchID")
-	}
-	if wc.Context.MchAPIKey == "" {
-		return fmt.Errorf("%s", "配置中没有MchAPIKey")
-	}
-	if wc.Context.SslCertFilePath == "" && wc.Context.SslCertContent == "" {
-		return fmt.Errorf("%s", "配置中没有SslCert")
-	}
-	if wc.Context.SslKeyFilePath == "" && wc.Context.SslKeyContent == "" {
-		return fmt.Errorf("%s", "配置中没有SslKey")
-	}
 	//初始化 http client, 有错误会出错误
 	err = wc.Context.InitHTTPClients()
 	return
@@ -113,21 +116,5 @@ func (wc *Wechat) checkCfgMch() (err error) {
 
 // 检查开放平台参数
 func (wc *Wechat) checkOpenPlatformConfig() (err error) {
-	if wc.Context.ComponentAppId == "" {
-		return fmt.Errorf("%s", "请指定第三方平台组件APPID")
-	}
-
-	if wc.Context.ComponentAppSecret == "" {
-		return fmt.Errorf("%s", "请指定第三方平台组件SECRET")
-	}
-
-	if wc.Context.ComponentAppToken == "" {
-		return fmt.Errorf("%s", "第三方平台组件TOKEN")
-	}
-
-	if wc.Context.ComponentAppKey == "" {
-		return fmt.Errorf("%s", " 第三方平台组件AESKEY")
-	}
-
-	return nil
+	return wc.Context.ValidateOpenPlatform()
 }
@@ -0,0 +1,96 @@
+package wxconfig
+
+import (
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/MrCHI/gowechat/wxcontext"
+)
+
+//LoadEnv 从环境变量读取账号配置，变量名为 prefix_ 加上 Account 的 json 标签的大写，
+//例如 WECHAT_APP_ID、WECHAT_MCH_API_KEY。
+//
+//多个账号时用 prefix_ACCOUNTS 列出账号名称（逗号分隔），每个账号的变量名中间加上名称的大写，
+//例如 WECHAT_ACCOUNTS=main,shop 时读取 WECHAT_MAIN_APP_ID、WECHAT_SHOP_APP_ID。
+//quota_limits 不支持从环境变量读取。
+func LoadEnv(prefix string) ([]wxcontext.Config, error) {
+	return loadEnv(prefix, os.LookupEnv)
+}
+
+func loadEnv(prefix string, lookup func(string) (string, bool)) ([]wxcontext.Config, error) {
+	prefix = strings.ToUpper(strings.TrimSuffix(prefix, "_")) + "_"
+	names, ok := lookup(prefix + "ACCOUNTS")
+	if !ok {
+		account, err := envAccount(prefix, lookup)
+		if err != nil {
+			return nil, err
+		}
+		return Build([]Account{account})
+	}
+
+	var accounts []Account
+	errs := new(wxcontext.ConfigError)
+	for _, name := range strings.Split(names, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		account, err := envAccount(prefix+strings.ToUpper(name)+"_", lookup)
+		errs.Merge("", err)
+		account.Name = name
+		accounts = append(accounts, account)
+	}
+	if err := errs.Err(); err != nil {
+		return nil, err
+	}
+	return Build(accounts)
+}
+
+//envAccount 按json标签读取一个账号的变量
+func envAccount(prefix string, lookup func(string) (string, bool)) (account Account, err error) {
+	errs := new(wxcontext.ConfigError)
+	v := reflect.ValueOf(&account).Elem()
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		tag := t.Field(i).Tag.Get("json")
+		if tag == "name" {
+			continue
+		}
+		key := prefix + strings.ToUpper(tag)
+		val, ok := lookup(key)
+		if !ok {
+			continue
+		}
+		field := v.Field(i)
+		switch field.Kind() {
+		case reflect.String:
+			field.SetString(val)
+		case reflect.Int:
+			n, e := strconv.Atoi(val)
+			if e != nil {
+				errs.Add(key, "不是整数: %q", val)
+				continue
+			}
+			field.SetInt(int64(n))
+		case reflect.Bool:
+			b, e := strconv.ParseBool(val)
+			if e != nil {
+				errs.Add(key, "不是布尔值: %q", val)
+				continue
+			}
+			field.SetBool(b)
+		case reflect.Float64:
+			f, e := strconv.ParseFloat(val, 64)
+			if e != nil {
+				errs.Add(key, "不是数字: %q", val)
+				continue
+			}
+			field.SetFloat(f)
+		default:
+			errs.Add(key, "不支持从环境变量读取")
+		}
+	}
+	return account, errs.Err()
+}
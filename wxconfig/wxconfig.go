@@ -0,0 +1,229 @@
+//Package wxconfig 从JSON、YAML、TOML文件或环境变量读取账号配置，并一次性校验所有账号
+//
+//文件中用 accounts 列出账号（可以有多个），key 与 Account 的 json 标签一致：
+//
+//	accounts:
+//	  - name: main
+//	    app_id: wx1234567890abcdef
+//	    app_secret: ...
+//	    token: ...
+//	    encoding_aes_key: ...
+//	    encrypt_mode: compatible
+//	    http_timeout: 30s
+//
+//校验失败时返回 *wxcontext.ConfigError，包含所有账号的所有问题。
+package wxconfig
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/MrCHI/gowechat/wxcontext"
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+//文件格式
+const (
+	FormatJSON = "json"
+	FormatYAML = "yaml"
+	FormatTOML = "toml"
+)
+
+//File 配置文件的结构
+type File struct {
+	Accounts []Account `json:"accounts" yaml:"accounts" toml:"accounts"`
+}
+
+//Account 一个账号的配置，对应 wxcontext.Config 中可以写在文件里的字段
+type Account struct {
+	//Name 账号名称，只用于错误信息和环境变量前缀
+	Name string `json:"name" yaml:"name" toml:"name"`
+
+	AppID          string `json:"app_id"           yaml:"app_id"           toml:"app_id"`
+	AppSecret      string `json:"app_secret"       yaml:"app_secret"       toml:"app_secret"`
+	OriginalID     string `json:"original_id"      yaml:"original_id"      toml:"original_id"`
+	Token          string `json:"token"            yaml:"token"            toml:"token"`
+	EncodingAESKey string `json:"encoding_aes_key" yaml:"encoding_aes_key" toml:"encoding_aes_key"`
+	EncryptMode    string `json:"encrypt_mode"     yaml:"encrypt_mode"     toml:"encrypt_mode"`
+	UseStableToken bool   `json:"use_stable_token" yaml:"use_stable_token" toml:"use_stable_token"`
+
+	MchID           string `json:"mch_id"             yaml:"mch_id"             toml:"mch_id"`
+	MchAPIKey       string `json:"mch_api_key"        yaml:"mch_api_key"        toml:"mch_api_key"`
+	SslCertFilePath string `json:"ssl_cert_file_path" yaml:"ssl_cert_file_path" toml:"ssl_cert_file_path"`
+	SslKeyFilePath  string `json:"ssl_key_file_path"  yaml:"ssl_key_file_path"  toml:"ssl_key_file_path"`
+	SslCertContent  string `json:"ssl_cert_content"   yaml:"ssl_cert_content"   toml:"ssl_cert_content"`
+	SslKeyContent   string `json:"ssl_key_content"    yaml:"ssl_key_content"    toml:"ssl_key_content"`
+
+	ComponentAppId     string `json:"component_app_id"     yaml:"component_app_id"     toml:"component_app_id"`
+	ComponentAppSecret string `json:"component_app_secret" yaml:"component_app_secret" toml:"component_app_secret"`
+	ComponentAppToken  string `json:"component_app_token"  yaml:"component_app_token"  toml:"component_app_token"`
+	ComponentAppKey    string `json:"component_app_key"    yaml:"component_app_key"    toml:"component_app_key"`
+
+	//HTTPTimeout 例如 "30s"
+	HTTPTimeout             string `json:"http_timeout"                 yaml:"http_timeout"                 toml:"http_timeout"`
+	HTTPProxy               string `json:"http_proxy"                   yaml:"http_proxy"                   toml:"http_proxy"`
+	HTTPMaxIdleConnsPerHost int    `json:"http_max_idle_conns_per_host" yaml:"http_max_idle_conns_per_host" toml:"http_max_idle_conns_per_host"`
+
+	//MsgDedupWindow 例如 "1m"，"-1s" 表示不去重
+	MsgDedupWindow string `json:"msg_dedup_window" yaml:"msg_dedup_window" toml:"msg_dedup_window"`
+	MsgDedupReplay bool   `json:"msg_dedup_replay" yaml:"msg_dedup_replay" toml:"msg_dedup_replay"`
+	//MsgReplyTimeout 例如 "3s"，"-1s" 表示不限制
+	MsgReplyTimeout string `json:"msg_reply_timeout" yaml:"msg_reply_timeout" toml:"msg_reply_timeout"`
+
+	CountQuota     bool             `json:"count_quota"      yaml:"count_quota"      toml:"count_quota"`
+	QuotaLimits    map[string]int64 `json:"quota_limits"     yaml:"quota_limits"     toml:"quota_limits"`
+	QuotaWarnRatio float64          `json:"quota_warn_ratio" yaml:"quota_warn_ratio" toml:"quota_warn_ratio"`
+}
+
+//Config 转换为 wxcontext.Config，不做校验
+func (a *Account) Config() (cfg wxcontext.Config, err error) {
+	cfg = wxcontext.Config{
+		AppID:                   a.AppID,
+		AppSecret:               a.AppSecret,
+		OriginalID:              a.OriginalID,
+		Token:                   a.Token,
+		EncodingAESKey:          a.EncodingAESKey,
+		EncryptMode:             wxcontext.EncryptMode(a.EncryptMode),
+		UseStableToken:          a.UseStableToken,
+		MchID:                   a.MchID,
+		MchAPIKey:               a.MchAPIKey,
+		SslCertFilePath:         a.SslCertFilePath,
+		SslKeyFilePath:          a.SslKeyFilePath,
+		SslCertContent:          a.SslCertContent,
+		SslKeyContent:           a.SslKeyContent,
+		ComponentAppId:          a.ComponentAppId,
+		ComponentAppSecret:      a.ComponentAppSecret,
+		ComponentAppToken:       a.ComponentAppToken,
+		ComponentAppKey:         a.ComponentAppKey,
+		HTTPProxy:               a.HTTPProxy,
+		HTTPMaxIdleConnsPerHost: a.HTTPMaxIdleConnsPerHost,
+		CountQuota:              a.CountQuota,
+		QuotaLimits:             a.QuotaLimits,
+		QuotaWarnRatio:          a.QuotaWarnRatio,
+		MsgDedupReplay:          a.MsgDedupReplay,
+	}
+	if a.HTTPTimeout != "" {
+		if cfg.HTTPTimeout, err = time.ParseDuration(a.HTTPTimeout); err != nil {
+			err = fmt.Errorf("http_timeout %q 无效", a.HTTPTimeout)
+		}
+	}
+	if a.MsgDedupWindow != "" && err == nil {
+		if cfg.MsgDedupWindow, err = time.ParseDuration(a.MsgDedupWindow); err != nil {
+			err = fmt.Errorf("msg_dedup_window %q 无效", a.MsgDedupWindow)
+		}
+	}
+	if a.MsgReplyTimeout != "" && err == nil {
+		if cfg.MsgReplyTimeout, err = time.ParseDuration(a.MsgReplyTimeout); err != nil {
+			err = fmt.Errorf("msg_reply_timeout %q 无效", a.MsgReplyTimeout)
+		}
+	}
+	return
+}
+
+//Load 读取配置文件，按扩展名（.json .yaml .yml .toml）判断格式
+func Load(path string) ([]wxcontext.Config, error) {
+	var format string
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		format = FormatJSON
+	case ".yaml", ".yml":
+		format = FormatYAML
+	case ".toml":
+		format = FormatTOML
+	default:
+		return nil, fmt.Errorf("不支持的配置文件格式: %s", path)
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data, format)
+}
+
+//Parse 解析配置内容并校验所有账号，不认识的key会报错
+func Parse(data []byte, format string) ([]wxcontext.Config, error) {
+	var file File
+	var err error
+	switch format {
+	case FormatJSON:
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		err = dec.Decode(&file)
+	case FormatYAML:
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		err = dec.Decode(&file)
+	case FormatTOML:
+		dec := toml.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		err = dec.Decode(&file)
+	default:
+		return nil, fmt.Errorf("不支持的配置格式: %s", format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("解析%s配置失败: %v", format, err)
+	}
+	return Build(file.Accounts)
+}
+
+//Build 把账号转换为 wxcontext.Config 并校验，包括AppID、OriginalID不能重复
+func Build(accounts []Account) ([]wxcontext.Config, error) {
+	errs := new(wxcontext.ConfigError)
+	if len(accounts) == 0 {
+		errs.Add("accounts", "没有配置账号")
+	}
+	cfgs := make([]wxcontext.Config, 0, len(accounts))
+	appIDs := make(map[string]string)
+	originalIDs := make(map[string]string)
+	for i := range accounts {
+		account := &accounts[i]
+		label := fmt.Sprintf("accounts[%d]", i)
+		if account.Name != "" {
+			label = fmt.Sprintf("accounts[%d](%s)", i, account.Name)
+		}
+
+		cfg, err := account.Config()
+		errs.Merge(label, err)
+		errs.Merge(label, fileKeys(cfg.Validate()))
+
+		if prev, ok := appIDs[cfg.AppID]; ok && cfg.AppID != "" {
+			errs.Add(label+".app_id", "与%s重复", prev)
+		}
+		appIDs[cfg.AppID] = label
+		if prev, ok := originalIDs[cfg.OriginalID]; ok && cfg.OriginalID != "" {
+			errs.Add(label+".original_id", "与%s重复", prev)
+		}
+		originalIDs[cfg.OriginalID] = label
+		cfgs = append(cfgs, cfg)
+	}
+	if err := errs.Err(); err != nil {
+		return nil, err
+	}
+	return cfgs, nil
+}
+
+//fileKeys 把校验结果中的字段名换成配置文件中的key
+func fileKeys(err error) error {
+	cfgErr, ok := err.(*wxcontext.ConfigError)
+	if !ok {
+		return err
+	}
+	t := reflect.TypeOf(Account{})
+	for i, p := range cfgErr.Problems {
+		name := p.Field
+		if idx := strings.IndexByte(name, '['); idx >= 0 {
+			name = name[:idx]
+		}
+		if f, ok := t.FieldByName(name); ok {
+			cfgErr.Problems[i].Field = f.Tag.Get("json") + p.Field[len(name):]
+		}
+	}
+	return cfgErr
+}
@@ -0,0 +1,128 @@
+package wxconfig
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/MrCHI/gowechat/wxcontext"
+)
+
+const aesKey = "abcdefghijklmnopqrstuvwxyz0123456789ABCDEFG"
+
+func TestParseFormats(t *testing.T) {
+	docs := map[string]string{
+		FormatJSON: `{"accounts": [
+			{"name": "main", "app_id": "wx1", "app_secret": "s1", "token": "t1", "encoding_aes_key": "` + aesKey + `", "http_timeout": "30s"},
+			{"app_id": "wx2", "app_secret": "s2", "token": "t2", "quota_limits": {"/cgi-bin/menu/create": 100}}
+		]}`,
+		FormatYAML: `
+accounts:
+  - name: main
+    app_id: wx1
+    app_secret: s1
+    token: t1
+    encoding_aes_key: ` + aesKey + `
+    http_timeout: 30s
+  - app_id: wx2
+    app_secret: s2
+    token: t2
+    quota_limits:
+      /cgi-bin/menu/create: 100
+`,
+		FormatTOML: `
+[[accounts]]
+name = "main"
+app_id = "wx1"
+app_secret = "s1"
+token = "t1"
+encoding_aes_key = "` + aesKey + `"
+http_timeout = "30s"
+
+[[accounts]]
+app_id = "wx2"
+app_secret = "s2"
+token = "t2"
+quota_limits = { "/cgi-bin/menu/create" = 100 }
+`,
+	}
+	for format, doc := range docs {
+		cfgs, err := Parse([]byte(doc), format)
+		if err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		if len(cfgs) != 2 || cfgs[0].AppID != "wx1" || cfgs[0].HTTPTimeout != 30*time.Second ||
+			cfgs[1].QuotaLimits["/cgi-bin/menu/create"] != 100 {
+			t.Errorf("%s: unexpected configs %+v", format, cfgs)
+		}
+	}
+}
+
+func TestParseReportsAllProblems(t *testing.T) {
+	doc := `
+accounts:
+  - name: main
+    app_id: wx1
+    token: t1
+    encoding_aes_key: short
+    mch_id: "1900000109"
+    ssl_cert_content: not a cert
+    ssl_key_content: not a key
+  - app_id: wx1
+    app_secret: s2
+    token: t2
+    encrypt_mode: secure
+    http_timeout: soon
+`
+	_, err := Parse([]byte(doc), FormatYAML)
+	cfgErr, ok := err.(*wxcontext.ConfigError)
+	if !ok {
+		t.Fatalf("expected *ConfigError, got %v", err)
+	}
+	want := []string{
+		"accounts[0](main).app_secret",
+		"accounts[0](main).encoding_aes_key",
+		"accounts[0](main).mch_api_key",
+		"accounts[0](main).SslCert",
+		"accounts[1]",
+		"accounts[1].encrypt_mode",
+		"accounts[1].app_id",
+	}
+	var got []string
+	for _, p := range cfgErr.Problems {
+		got = append(got, p.Field)
+	}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("problems %v\nwant fields %v", cfgErr, want)
+	}
+}
+
+func TestParseUnknownKey(t *testing.T) {
+	if _, err := Parse([]byte(`{"accounts": [{"appid": "wx1"}]}`), FormatJSON); err == nil {
+		t.Error("expected error for unknown key")
+	}
+}
+
+func TestLoadEnv(t *testing.T) {
+	env := map[string]string{
+		"WECHAT_ACCOUNTS":              "main, shop",
+		"WECHAT_MAIN_APP_ID":           "wx1",
+		"WECHAT_MAIN_APP_SECRET":       "s1",
+		"WECHAT_MAIN_TOKEN":            "t1",
+		"WECHAT_SHOP_APP_ID":           "wx2",
+		"WECHAT_SHOP_APP_SECRET":       "s2",
+		"WECHAT_SHOP_TOKEN":            "t2",
+		"WECHAT_SHOP_QUOTA_WARN_RATIO": "0.9",
+	}
+	lookup := func(key string) (string, bool) {
+		val, ok := env[key]
+		return val, ok
+	}
+	cfgs, err := loadEnv("WECHAT", lookup)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfgs) != 2 || cfgs[1].AppID != "wx2" || cfgs[1].QuotaWarnRatio != 0.9 {
+		t.Errorf("unexpected configs %+v", cfgs)
+	}
+}
@@ -1,17 +1,20 @@
 package wxcontext
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"sync"
 	"time"
 
-	"github.com/yaotian/gowechat/util"
+	"github.com/MrCHI/gowechat/util"
 )
 
 const (
 	//AccessTokenURL 获取access_token的接口
 	AccessTokenURL = "https://api.weixin.qq.com/cgi-bin/token"
+	//StableAccessTokenURL 获取稳定版access_token的接口，见 Config.UseStableToken
+	StableAccessTokenURL = "https://api.weixin.qq.com/cgi-bin/stable_token"
 )
 
 //ResAccessToken struct
@@ -22,45 +25,122 @@ type ResAccessToken struct {
 	ExpiresIn   int64  `json:"expires_in"`
 }
 
+//stableTokenRequest stable_token的请求参数
+type stableTokenRequest struct {
+	GrantType    string `json:"grant_type"`
+	AppID        string `json:"appid"`
+	Secret       string `json:"secret"`
+	ForceRefresh bool   `json:"force_refresh"`
+}
+
+//SetAccessTokenLocker 设置互斥锁（一个appID一个锁）
+func (ctx *Context) SetAccessTokenLocker(l *TokenLock) {
+	ctx.accessTokenLock = l
+}
+
 //SetAccessTokenLock 设置读写锁（一个appID一个读写锁）
+//
+//Deprecated: 等待*sync.RWMutex时不能被context取消，使用 SetAccessTokenLocker
 func (ctx *Context) SetAccessTokenLock(l *sync.RWMutex) {
-	ctx.accessTokenLock = l
+	ctx.accessTokenLock = &TokenLock{mu: l}
 }
 
 //GetAccessToken 获取access_token
 func (ctx *Context) GetAccessToken() (accessToken string, err error) {
-	ctx.accessTokenLock.Lock()
-	defer ctx.accessTokenLock.Unlock()
+	return ctx.GetAccessTokenContext(context.Background())
+}
 
-	accessTokenCacheKey := fmt.Sprintf("access_token_%s", ctx.AppID)
-	val := ctx.Cache.Get(accessTokenCacheKey)
-	if val != nil {
-		accessToken = val.(string)
+//GetAccessTokenContext 获取access_token，reqCtx结束时停止等待锁和请求
+func (ctx *Context) GetAccessTokenContext(reqCtx context.Context) (accessToken string, err error) {
+	if err = ctx.accessTokenLock.Lock(reqCtx); err != nil {
 		return
 	}
+	defer ctx.accessTokenLock.Unlock()
 
-	//从微信服务器获取
-	var resAccessToken ResAccessToken
-	resAccessToken, err = ctx.GetAccessTokenFromServer()
+	return ctx.RefreshToken(reqCtx, ctx.accessTokenCacheKey(), ctx.fetchAccessToken)
+}
+
+//fetchAccessToken 从微信服务器获取，实现FetchTokenFunc
+func (ctx *Context) fetchAccessToken(reqCtx context.Context) (string, time.Duration, error) {
+	resAccessToken, err := ctx.requestAccessToken(reqCtx, false)
 	if err != nil {
-		return
+		return "", 0, err
 	}
+	return resAccessToken.AccessToken, accessTokenExpires(resAccessToken.ExpiresIn), nil
+}
 
-	accessToken = resAccessToken.AccessToken
-	return
+//AccessTokenRefreshTask 供 Refresher 使用，提前刷新access_token
+func (ctx *Context) AccessTokenRefreshTask() RefreshTask {
+	return RefreshTask{Name: "access_token", Refresh: func(reqCtx context.Context) (next time.Duration, err error) {
+		if err = ctx.accessTokenLock.Lock(reqCtx); err != nil {
+			return
+		}
+		defer ctx.accessTokenLock.Unlock()
+
+		return ctx.RenewToken(reqCtx, ctx.accessTokenCacheKey(), ctx.fetchAccessToken)
+	}}
 }
 
 //CleanAccessTokenCache clean cache
 func (ctx *Context) CleanAccessTokenCache() {
-	accessTokenCacheKey := fmt.Sprintf("access_token_%s", ctx.AppID)
-	ctx.Cache.Delete(accessTokenCacheKey)
+	ctx.Cache.Delete(ctx.accessTokenCacheKey())
+}
+
+//accessTokenCacheKey 稳定版和普通的access_token分开缓存
+func (ctx *Context) accessTokenCacheKey() string {
+	if ctx.UseStableToken {
+		return fmt.Sprintf("stable_access_token_%s", ctx.AppID)
+	}
+	return fmt.Sprintf("access_token_%s", ctx.AppID)
 }
 
 //GetAccessTokenFromServer 强制从微信服务器获取token
 func (ctx *Context) GetAccessTokenFromServer() (resAccessToken ResAccessToken, err error) {
-	url := fmt.Sprintf("%s?grant_type=client_credential&appid=%s&secret=%s", AccessTokenURL, ctx.AppID, ctx.AppSecret)
+	return ctx.GetAccessTokenFromServerContext(context.Background())
+}
+
+//GetAccessTokenFromServerContext 同 GetAccessTokenFromServer，支持 context
+//
+//稳定版模式下，token没有过期时微信返回的仍然是同一个token
+func (ctx *Context) GetAccessTokenFromServerContext(reqCtx context.Context) (resAccessToken ResAccessToken, err error) {
+	return ctx.requestAndCacheAccessToken(reqCtx, false)
+}
+
+//ForceRefreshAccessToken 稳定版模式下强制刷新access_token（force_refresh=true），之前的token立即失效，
+//每天最多20次，只应在确认token泄露等情况下使用；普通模式下等同 GetAccessTokenFromServer
+func (ctx *Context) ForceRefreshAccessToken() (resAccessToken ResAccessToken, err error) {
+	return ctx.ForceRefreshAccessTokenContext(context.Background())
+}
+
+//ForceRefreshAccessTokenContext 同 ForceRefreshAccessToken，支持 context
+func (ctx *Context) ForceRefreshAccessTokenContext(reqCtx context.Context) (resAccessToken ResAccessToken, err error) {
+	return ctx.requestAndCacheAccessToken(reqCtx, true)
+}
+
+func (ctx *Context) requestAndCacheAccessToken(reqCtx context.Context, forceRefresh bool) (resAccessToken ResAccessToken, err error) {
+	if resAccessToken, err = ctx.requestAccessToken(reqCtx, forceRefresh); err != nil {
+		return
+	}
+	err = ctx.Cache.Put(ctx.accessTokenCacheKey(), resAccessToken.AccessToken, accessTokenExpires(resAccessToken.ExpiresIn))
+	return
+}
+
+//requestAccessToken 请求微信服务器，不写缓存；forceRefresh只在稳定版模式下有效
+func (ctx *Context) requestAccessToken(reqCtx context.Context, forceRefresh bool) (resAccessToken ResAccessToken, err error) {
+	endpoint := AccessTokenURL
 	var body []byte
-	body, err = util.HTTPGet(url)
+	if ctx.UseStableToken {
+		endpoint = StableAccessTokenURL
+		body, err = ctx.PostJSONContext(reqCtx, ctx.ResolveURL(StableAccessTokenURL), stableTokenRequest{
+			GrantType:    "client_credential",
+			AppID:        ctx.AppID,
+			Secret:       ctx.AppSecret,
+			ForceRefresh: forceRefresh,
+		})
+	} else {
+		url := fmt.Sprintf("%s?grant_type=client_credential&appid=%s&secret=%s", ctx.ResolveURL(AccessTokenURL), ctx.AppID, ctx.AppSecret)
+		body, err = ctx.HTTPGetContext(reqCtx, url)
+	}
 	if err != nil {
 		return
 	}
@@ -68,13 +148,18 @@ func (ctx *Context) GetAccessTokenFromServer() (resAccessToken ResAccessToken, e
 	if err != nil {
 		return
 	}
-	if resAccessToken.ErrMsg != "" {
-		err = fmt.Errorf("get access_token error : errcode=%v , errormsg=%v", resAccessToken.ErrCode, resAccessToken.ErrMsg)
+	if resAccessToken.ErrCode != 0 {
+		err = util.NewAPIError(endpoint, resAccessToken.ErrCode, resAccessToken.ErrMsg)
+		ctx.CountCall(reqCtx, endpoint, err)
+		ctx.Log(reqCtx, LevelError, "获取access_token失败", "error", err)
 		return
 	}
-
-	accessTokenCacheKey := fmt.Sprintf("access_token_%s", ctx.AppID)
-	expires := resAccessToken.ExpiresIn - 1500
-	err = ctx.Cache.Put(accessTokenCacheKey, resAccessToken.AccessToken, time.Duration(expires)*time.Second)
+	ctx.CountCall(reqCtx, endpoint, nil)
+	ctx.Log(reqCtx, LevelInfo, "获取access_token", "expires_in", resAccessToken.ExpiresIn, "stable", ctx.UseStableToken, "force_refresh", forceRefresh)
 	return
 }
+
+//accessTokenExpires 提前过期，留出刷新的时间
+func accessTokenExpires(expiresIn int64) time.Duration {
+	return time.Duration(expiresIn-1500) * time.Second
+}
@@ -1,14 +1,73 @@
 package wxcontext
 
-import "github.com/astaxie/beego/cache"
+import (
+	"net/http"
+	"time"
+
+	"github.com/MrCHI/gowechat/cache"
+)
+
+const (
+	//DefaultMsgDedupWindow 默认的推送消息去重时间，覆盖微信的3次重试
+	DefaultMsgDedupWindow = time.Minute
+	//DefaultMsgReplyTimeout 默认的推送消息处理时间限制，微信要求5秒内回复
+	DefaultMsgReplyTimeout = 4 * time.Second
+)
+
+//EncryptMode 消息加解密方式
+type EncryptMode string
+
+const (
+	//EncryptModePlain 明文模式，只接受明文推送
+	EncryptModePlain EncryptMode = "plain"
+	//EncryptModeCompatible 兼容模式，推送中同时有明文和密文，优先使用密文，回复的格式与推送一致
+	EncryptModeCompatible EncryptMode = "compatible"
+	//EncryptModeSafe 安全模式，只接受密文推送
+	EncryptModeSafe EncryptMode = "safe"
+)
 
 // Config for user
 type Config struct {
 	AppID          string
 	AppSecret      string
+	OriginalID     string // 公众号原始ID（gh_开头），收到消息时用于按ToUserName查找账号
 	Token          string
 	EncodingAESKey string
-	Cache          cache.Cache
+	Cache          cache.Cache // 为空时使用进程内的内存缓存
+
+	//EncryptMode 消息加解密方式，与公众平台后台的设置一致；为空时按推送中是否带密文判断，等同兼容模式
+	EncryptMode EncryptMode
+
+	//MsgDedupWindow 推送消息的去重时间，默认 DefaultMsgDedupWindow，小于0时不去重。
+	//微信5秒内没有收到回复会重试，最多3次；窗口内重复的推送不再调用处理方法，记录保存在Cache中
+	MsgDedupWindow time.Duration
+	//MsgDedupReplay 重复的推送回复第一次处理的结果（第一次仍在处理或没有回复时回复success），为false时回复success
+	MsgDedupReplay bool
+	//MsgReplyTimeout 处理推送消息的时间限制，默认 DefaultMsgReplyTimeout，小于0时不限制。
+	//超时后先回复success，处理方法之后返回的回复通过客服消息接口发给用户
+	MsgReplyTimeout time.Duration
+
+	//UseStableToken 使用 /cgi-bin/stable_token 获取access_token。
+	//普通模式下每次获取都会使之前的token失效，多个系统共用一个AppID时会互相影响；稳定版的token在有效期内重复获取不会变化
+	UseStableToken bool
+
+	//Endpoint 接口地址解析，为空时使用微信官方域名
+	Endpoint EndpointResolver
+
+	//Logger 日志，为空时不输出
+	Logger Logger
+
+	//CountQuota 在Cache中按天记录接口调用次数，接近上限时告警；每次调用会多两次Cache读写，默认关闭
+	CountQuota bool
+	//QuotaLimits 接口每日调用上限，key为接口路径，覆盖 DefaultQuotaLimits
+	QuotaLimits map[string]int64
+	//QuotaWarnRatio 调用次数达到上限的该比例时告警，默认 DefaultQuotaWarnRatio
+	QuotaWarnRatio float64
+	//OnQuotaWarning 调用次数达到告警比例或上限时调用，同时会输出Warn日志
+	OnQuotaWarning func(appID, endpoint string, used, limit int64)
+
+	//Interceptors 每次调用微信接口、处理推送消息前后调用，见 metrics.Collector；需要在第一次请求前设置
+	Interceptors []Interceptor
 
 	// 商户平台参数
 	MchID           string
@@ -23,4 +82,11 @@ type Config struct {
 	ComponentAppSecret string // 第三方平台组件SECRET
 	ComponentAppToken  string // 第三方平台组件TOKEN
 	ComponentAppKey    string // 第三方平台组件AESKEY
+
+	// HTTP 参数，每个账号可以单独设置
+	HTTPTimeout             time.Duration     // 请求超时，默认60秒
+	HTTPProxy               string            // 代理地址，为空时读取环境变量 HTTP_PROXY/HTTPS_PROXY
+	HTTPMaxIdleConnsPerHost int               // 每个域名保留的空闲连接数，默认 http.DefaultMaxIdleConnsPerHost
+	HTTPTransport           http.RoundTripper // 自定义底层Transport，设置后 HTTPProxy、HTTPMaxIdleConnsPerHost 不生效（双向证书请求除外）
+	HTTPMiddlewares         []HTTPMiddleware  // 按顺序包裹在Transport外层，先添加的在最外层
 }
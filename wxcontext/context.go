@@ -1,27 +1,29 @@
 package wxcontext
 
 import (
+	"context"
 	"net/http"
 	"sync"
-
-	"github.com/MrCHI/gowechat/util"
+	"time"
 )
 
 // Context struct
 type Context struct {
 	*Config
 
+	//Writer Request 只在 WithRequest 返回的Context中设置，不要修改多个请求共用的Context
 	Writer  http.ResponseWriter
 	Request *http.Request
 
-	//accessTokenLock 读写锁 同一个AppID一个
-	accessTokenLock *sync.RWMutex
+	//accessTokenLock 互斥锁 同一个AppID一个
+	accessTokenLock *TokenLock
 
-	//jsAPITicket 读写锁 同一个AppID一个
-	jsAPITicketLock *sync.RWMutex
+	//jsAPITicket 互斥锁 同一个AppID一个
+	jsAPITicketLock *TokenLock
 
 	HTTPClient  *http.Client
 	SHTTPClient *http.Client //SSL client
+	httpLock    sync.Mutex
 }
 
 // Query returns the keyed url query value if it exists
@@ -39,34 +41,69 @@ func (ctx *Context) GetQuery(key string) (string, bool) {
 	return "", false
 }
 
-// SetJsAPITicketLock 设置jsAPITicket的lock
-func (ctx *Context) SetJsAPITicketLock(lock *sync.RWMutex) {
+// RequestContext 返回当前请求的context，没有请求时返回context.Background()
+func (ctx *Context) RequestContext() context.Context {
+	if ctx.Request == nil {
+		return context.Background()
+	}
+	return ctx.Request.Context()
+}
+
+//WithRequest 返回处理一次请求使用的Context，与ctx共用配置、锁和http client，
+//并发处理请求时每个请求一个，不会互相影响
+func (ctx *Context) WithRequest(req *http.Request, writer http.ResponseWriter) *Context {
+	client := ctx.GetHTTPClient()
+	ctx.httpLock.Lock()
+	sclient := ctx.SHTTPClient
+	ctx.httpLock.Unlock()
+	return &Context{
+		Config:          ctx.Config,
+		Writer:          writer,
+		Request:         req,
+		accessTokenLock: ctx.accessTokenLock,
+		jsAPITicketLock: ctx.jsAPITicketLock,
+		HTTPClient:      client,
+		SHTTPClient:     sclient,
+	}
+}
+
+//Detach 返回不会被取消、没有截止时间的ctx，保留parent中的值（例如请求ID），用于请求结束后继续执行的任务
+func Detach(parent context.Context) context.Context {
+	return detachedContext{parent}
+}
+
+type detachedContext struct {
+	parent context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool)         { return time.Time{}, false }
+func (detachedContext) Done() <-chan struct{}               { return nil }
+func (detachedContext) Err() error                          { return nil }
+func (c detachedContext) Value(key interface{}) interface{} { return c.parent.Value(key) }
+
+// SetJsAPITicketLocker 设置jsAPITicket的lock
+func (ctx *Context) SetJsAPITicketLocker(lock *TokenLock) {
 	ctx.jsAPITicketLock = lock
 }
 
-// GetJsAPITicketLock 获取jsAPITicket 的lock
-func (ctx *Context) GetJsAPITicketLock() *sync.RWMutex {
+// GetJsAPITicketLocker 获取jsAPITicket 的lock
+func (ctx *Context) GetJsAPITicketLocker() *TokenLock {
 	return ctx.jsAPITicketLock
 }
 
-//InitHTTPClients Context中初始化 httpclient httpsclient
-func (ctx *Context) InitHTTPClients() (err error) {
-	//create http client
-	if ctx.SslCertFilePath != "" && ctx.SslKeyFilePath != "" {
-		if client, err := util.NewTLSHttpClient(ctx.SslCertFilePath, ctx.SslKeyFilePath); err == nil {
-			ctx.SHTTPClient = client
-		} else {
-			return err
-		}
-	}
+// SetJsAPITicketLock 设置jsAPITicket的lock
+//
+// Deprecated: 等待*sync.RWMutex时不能被context取消，使用 SetJsAPITicketLocker
+func (ctx *Context) SetJsAPITicketLock(lock *sync.RWMutex) {
+	ctx.jsAPITicketLock = &TokenLock{mu: lock}
+}
 
-	if ctx.SslCertContent != "" && ctx.SslKeyContent != "" {
-		if client, err := util.NewTLSHttpClientFromContent(ctx.SslCertContent, ctx.SslKeyContent); err == nil {
-			ctx.SHTTPClient = client
-		} else {
-			return err
-		}
+// GetJsAPITicketLock 获取jsAPITicket 的lock，只返回 SetJsAPITicketLock 设置的锁，否则为nil
+//
+// Deprecated: 使用 GetJsAPITicketLocker
+func (ctx *Context) GetJsAPITicketLock() *sync.RWMutex {
+	if ctx.jsAPITicketLock == nil {
+		return nil
 	}
-	ctx.HTTPClient = http.DefaultClient
-	return
+	return ctx.jsAPITicketLock.mu
 }
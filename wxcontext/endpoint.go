@@ -0,0 +1,69 @@
+package wxcontext
+
+import "strings"
+
+const (
+	//HostAPI 公众平台、开放平台接口域名
+	HostAPI = "https://api.weixin.qq.com"
+	//HostMP 公众平台网页域名
+	HostMP = "https://mp.weixin.qq.com"
+	//HostOpen 网页授权域名
+	HostOpen = "https://open.weixin.qq.com"
+	//HostMch 商户平台接口域名
+	HostMch = "https://api.mch.weixin.qq.com"
+)
+
+//EndpointResolver 将SDK中的默认接口地址转换成实际请求的地址
+type EndpointResolver interface {
+	ResolveEndpoint(rawURL string) string
+}
+
+//EndpointResolverFunc 函数形式的EndpointResolver
+type EndpointResolverFunc func(rawURL string) string
+
+//ResolveEndpoint 实现EndpointResolver
+func (f EndpointResolverFunc) ResolveEndpoint(rawURL string) string {
+	return f(rawURL)
+}
+
+//Endpoints 按域名替换接口地址，为空的字段使用默认域名
+//例如测试时把所有域名指向 httptest.Server 的 URL
+type Endpoints struct {
+	API  string
+	MP   string
+	Open string
+	Mch  string
+}
+
+//AllEndpoints 所有域名都替换为同一个地址
+func AllEndpoints(host string) *Endpoints {
+	return &Endpoints{API: host, MP: host, Open: host, Mch: host}
+}
+
+//ResolveEndpoint 实现EndpointResolver
+func (e *Endpoints) ResolveEndpoint(rawURL string) string {
+	for _, h := range [...]struct{ from, to string }{
+		{HostAPI, e.API},
+		{HostMP, e.MP},
+		{HostOpen, e.Open},
+		{HostMch, e.Mch},
+	} {
+		if h.to == "" || !strings.HasPrefix(rawURL, h.from) {
+			continue
+		}
+		rest := rawURL[len(h.from):]
+		if rest != "" && rest[0] != '/' && rest[0] != '?' {
+			continue
+		}
+		return strings.TrimRight(h.to, "/") + rest
+	}
+	return rawURL
+}
+
+//ResolveURL 返回实际请求的地址，没有配置Endpoint时原样返回
+func (cfg *Config) ResolveURL(rawURL string) string {
+	if cfg.Endpoint == nil {
+		return rawURL
+	}
+	return cfg.Endpoint.ResolveEndpoint(rawURL)
+}
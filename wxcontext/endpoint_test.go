@@ -0,0 +1,31 @@
+package wxcontext
+
+import "testing"
+
+func TestResolveURL(t *testing.T) {
+	endpoints := &Endpoints{API: "http://127.0.0.1:8080/", Mch: "http://mch.local"}
+	tests := []struct {
+		endpoint EndpointResolver
+		rawURL   string
+		want     string
+	}{
+		{nil, HostAPI + "/cgi-bin/token?grant_type=client_credential", HostAPI + "/cgi-bin/token?grant_type=client_credential"},
+		{endpoints, HostAPI + "/cgi-bin/token?grant_type=client_credential", "http://127.0.0.1:8080/cgi-bin/token?grant_type=client_credential"},
+		{endpoints, HostAPI + "?a=1", "http://127.0.0.1:8080?a=1"},
+		{endpoints, HostAPI, "http://127.0.0.1:8080"},
+		{endpoints, HostMch + "/pay/unifiedorder", "http://mch.local/pay/unifiedorder"},
+		//没有配置的域名不替换
+		{endpoints, HostMP + "/cgi-bin/showqrcode?ticket=x", HostMP + "/cgi-bin/showqrcode?ticket=x"},
+		//只替换完整的域名
+		{endpoints, HostAPI + ".example.com/x", HostAPI + ".example.com/x"},
+		{endpoints, "https://example.com/cgi-bin/token", "https://example.com/cgi-bin/token"},
+		{AllEndpoints("http://mock"), HostOpen + "/connect/oauth2/authorize#wechat_redirect", "http://mock/connect/oauth2/authorize#wechat_redirect"},
+		{EndpointResolverFunc(func(rawURL string) string { return rawURL + "&debug=1" }), HostAPI + "/x?a=1", HostAPI + "/x?a=1&debug=1"},
+	}
+	for _, tt := range tests {
+		cfg := &Config{Endpoint: tt.endpoint}
+		if got := cfg.ResolveURL(tt.rawURL); got != tt.want {
+			t.Errorf("ResolveURL(%q) = %q, want %q", tt.rawURL, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package wxcontext
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/MrCHI/gowechat/util"
+)
+
+//DefaultHTTPTimeout 默认的请求超时
+const DefaultHTTPTimeout = 60 * time.Second
+
+//HTTPMiddleware 包裹出站请求的RoundTripper，可用于代理认证、故障注入、录制等
+type HTTPMiddleware func(next http.RoundTripper) http.RoundTripper
+
+//InitHTTPClients Context中初始化 httpclient httpsclient
+func (ctx *Context) InitHTTPClients() (err error) {
+	ctx.httpLock.Lock()
+	defer ctx.httpLock.Unlock()
+
+	var client *http.Client
+	if client, err = ctx.newHTTPClient(nil); err != nil {
+		return
+	}
+	ctx.HTTPClient = client
+
+	//双向证书
+	if client, err = ctx.newSHTTPClient(); err != nil || client == nil {
+		return
+	}
+	ctx.SHTTPClient = client
+	return
+}
+
+//newSHTTPClient 按配置的商户证书创建双向证书请求使用的client，没有配置证书时返回nil
+func (ctx *Context) newSHTTPClient() (*http.Client, error) {
+	var cert tls.Certificate
+	var err error
+	switch {
+	case ctx.SslCertContent != "" && ctx.SslKeyContent != "":
+		cert, err = tls.X509KeyPair([]byte(ctx.SslCertContent), []byte(ctx.SslKeyContent))
+	case ctx.SslCertFilePath != "" && ctx.SslKeyFilePath != "":
+		cert, err = tls.LoadX509KeyPair(ctx.SslCertFilePath, ctx.SslKeyFilePath)
+	default:
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("加载商户证书失败, err=%v", err)
+	}
+	return ctx.newHTTPClient(&tls.Config{Certificates: []tls.Certificate{cert}})
+}
+
+//GetHTTPClient 返回普通请求使用的client，没有初始化时按配置创建
+func (ctx *Context) GetHTTPClient() *http.Client {
+	ctx.httpLock.Lock()
+	defer ctx.httpLock.Unlock()
+	if ctx.HTTPClient == nil {
+		client, err := ctx.newHTTPClient(nil)
+		if err != nil {
+			//配置有误时所有请求都返回该错误，不能绕过代理、中间件直接发出
+			client = &http.Client{Transport: errTransport{err}}
+		}
+		ctx.HTTPClient = client
+	}
+	return ctx.HTTPClient
+}
+
+//errTransport 创建client失败时使用，每次请求都返回创建时的错误
+type errTransport struct {
+	err error
+}
+
+func (t errTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+	return nil, t.err
+}
+
+//GetSHTTPClient 返回双向证书请求使用的client
+func (ctx *Context) GetSHTTPClient() (*http.Client, error) {
+	ctx.httpLock.Lock()
+	defer ctx.httpLock.Unlock()
+	client := ctx.SHTTPClient
+	if client != nil {
+		return client, nil
+	}
+	client, err := ctx.newSHTTPClient()
+	if err != nil {
+		return nil, err
+	}
+	if client == nil {
+		return nil, fmt.Errorf("%s", "配置中没有SslCert或SslKey")
+	}
+	ctx.SHTTPClient = client
+	return client, nil
+}
+
+//HTTPGet 使用账号的client发送get请求
+func (ctx *Context) HTTPGet(uri string) ([]byte, error) {
+	return ctx.HTTPGetContext(context.Background(), uri)
+}
+
+//HTTPGetContext 同 HTTPGet，支持 context
+func (ctx *Context) HTTPGetContext(reqCtx context.Context, uri string) ([]byte, error) {
+	return util.HTTPGetContext(reqCtx, ctx.GetHTTPClient(), uri)
+}
+
+//PostJSON 使用账号的client发送json数据
+func (ctx *Context) PostJSON(uri string, obj interface{}) ([]byte, error) {
+	return ctx.PostJSONContext(context.Background(), uri, obj)
+}
+
+//PostJSONContext 同 PostJSON，支持 context
+func (ctx *Context) PostJSONContext(reqCtx context.Context, uri string, obj interface{}) ([]byte, error) {
+	return util.PostJSONContext(reqCtx, ctx.GetHTTPClient(), uri, obj)
+}
+
+//PostFile 使用账号的client上传文件
+func (ctx *Context) PostFile(fieldname, filename, uri string) ([]byte, error) {
+	return ctx.PostFileContext(context.Background(), fieldname, filename, uri)
+}
+
+//PostFileContext 同 PostFile，支持 context
+func (ctx *Context) PostFileContext(reqCtx context.Context, fieldname, filename, uri string) ([]byte, error) {
+	return util.PostFileContext(reqCtx, ctx.GetHTTPClient(), fieldname, filename, uri)
+}
+
+//PostMultipartForm 使用账号的client上传文件或其他多个字段，文件可以来自Reader，见 util.MultipartFormField
+func (ctx *Context) PostMultipartForm(fields []util.MultipartFormField, uri string) ([]byte, error) {
+	return ctx.PostMultipartFormContext(context.Background(), fields, uri)
+}
+
+//PostMultipartFormContext 同 PostMultipartForm，支持 context
+func (ctx *Context) PostMultipartFormContext(reqCtx context.Context, fields []util.MultipartFormField, uri string) ([]byte, error) {
+	return util.PostMultipartFormContext(reqCtx, ctx.GetHTTPClient(), fields, uri)
+}
+
+func (ctx *Context) httpTimeout() time.Duration {
+	if ctx.HTTPTimeout > 0 {
+		return ctx.HTTPTimeout
+	}
+	return DefaultHTTPTimeout
+}
+
+//newHTTPClient 按配置创建client，tlsConfig不为空时总是使用内置的Transport
+func (ctx *Context) newHTTPClient(tlsConfig *tls.Config) (*http.Client, error) {
+	var rt http.RoundTripper
+	if ctx.HTTPTransport != nil && tlsConfig == nil {
+		rt = ctx.HTTPTransport
+	} else {
+		proxy := http.ProxyFromEnvironment
+		if ctx.HTTPProxy != "" {
+			proxyURL, err := url.Parse(ctx.HTTPProxy)
+			if err != nil {
+				return nil, fmt.Errorf("HTTPProxy invalid, err=%v", util.RedactError(err))
+			}
+			proxy = http.ProxyURL(proxyURL)
+		}
+		rt = &http.Transport{
+			Proxy: proxy,
+			DialContext: (&net.Dialer{
+				Timeout:   30 * time.Second,
+				KeepAlive: 30 * time.Second,
+			}).DialContext,
+			MaxIdleConns:        100,
+			MaxIdleConnsPerHost: ctx.HTTPMaxIdleConnsPerHost,
+			IdleConnTimeout:     90 * time.Second,
+			TLSHandshakeTimeout: 10 * time.Second,
+			TLSClientConfig:     tlsConfig,
+		}
+	}
+	for i := len(ctx.HTTPMiddlewares) - 1; i >= 0; i-- {
+		rt = ctx.HTTPMiddlewares[i](rt)
+	}
+	if len(ctx.Interceptors) > 0 {
+		rt = &interceptTransport{cfg: ctx.Config, next: rt}
+	}
+	return &http.Client{
+		Transport: rt,
+		Timeout:   ctx.httpTimeout(),
+	}, nil
+}
@@ -0,0 +1,152 @@
+package wxcontext
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type roundTripFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestHTTPClientProxy(t *testing.T) {
+	var proxied string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = r.URL.String()
+		fmt.Fprint(w, "via proxy")
+	}))
+	defer proxy.Close()
+
+	ctx := &Context{Config: &Config{HTTPProxy: proxy.URL}}
+	body, err := ctx.HTTPGet("http://api.example.com/cgi-bin/token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(body) != "via proxy" || proxied != "http://api.example.com/cgi-bin/token" {
+		t.Errorf("request not sent through proxy: %q %q", body, proxied)
+	}
+}
+
+func TestHTTPClientInvalidProxy(t *testing.T) {
+	called := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer srv.Close()
+
+	ctx := &Context{Config: &Config{HTTPProxy: "http://proxy.local:%zz"}}
+	if _, err := ctx.HTTPGet(srv.URL); err == nil || !strings.Contains(err.Error(), "HTTPProxy") {
+		t.Errorf("want HTTPProxy error, got %v", err)
+	}
+	if called {
+		t.Error("request bypassed the invalid proxy")
+	}
+	if err := ctx.Config.Validate(); err == nil || !strings.Contains(err.Error(), "HTTPProxy") {
+		t.Errorf("Validate should reject the proxy, got %v", err)
+	}
+}
+
+func TestHTTPClientTransportAndMiddlewares(t *testing.T) {
+	var trace []string
+	middleware := func(name string) HTTPMiddleware {
+		return func(next http.RoundTripper) http.RoundTripper {
+			return roundTripFunc(func(req *http.Request) (*http.Response, error) {
+				trace = append(trace, name)
+				return next.RoundTrip(req)
+			})
+		}
+	}
+	ctx := &Context{Config: &Config{
+		HTTPTransport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			trace = append(trace, "transport")
+			return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader("ok")), Request: req}, nil
+		}),
+		HTTPMiddlewares: []HTTPMiddleware{middleware("outer"), middleware("inner")},
+	}}
+	body, err := ctx.HTTPGet("https://api.weixin.qq.com/cgi-bin/token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(body) != "ok" || strings.Join(trace, ",") != "outer,inner,transport" {
+		t.Errorf("unexpected body %q, trace %v", body, trace)
+	}
+}
+
+//newTestCert 生成自签名的商户证书
+func newTestCert(t *testing.T) (certPEM, keyPEM string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "test"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certPEM = string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+	keyPEM = string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}))
+	return
+}
+
+func TestGetSHTTPClient(t *testing.T) {
+	if _, err := (&Context{Config: &Config{}}).GetSHTTPClient(); err == nil {
+		t.Error("want error without cert")
+	}
+
+	certPEM, keyPEM := newTestCert(t)
+	ctx := &Context{Config: &Config{SslCertContent: certPEM, SslKeyContent: keyPEM}}
+	client, err := ctx.GetSHTTPClient()
+	if err != nil || client == nil {
+		t.Fatalf("GetSHTTPClient: %v %v", client, err)
+	}
+	if ctx.HTTPClient != nil {
+		t.Error("GetSHTTPClient should not replace HTTPClient")
+	}
+	if again, _ := ctx.GetSHTTPClient(); again != client {
+		t.Error("GetSHTTPClient should reuse the client")
+	}
+
+	//和InitHTTPClients并发调用，-race下检查数据竞争
+	ctx = &Context{Config: &Config{SslCertContent: certPEM, SslKeyContent: keyPEM}}
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			if c, err := ctx.GetSHTTPClient(); err != nil || c == nil {
+				t.Errorf("GetSHTTPClient: %v %v", c, err)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			if err := ctx.InitHTTPClients(); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+}
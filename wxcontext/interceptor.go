@@ -0,0 +1,138 @@
+package wxcontext
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/MrCHI/gowechat/util"
+)
+
+const (
+	//CallOutbound 调用微信接口
+	CallOutbound = "outbound"
+	//CallInbound 处理微信推送的消息
+	CallInbound = "inbound"
+)
+
+//CallInfo 一次调用的信息
+type CallInfo struct {
+	Kind     string        // CallOutbound 或 CallInbound
+	Endpoint string        // 接口路径（不含参数），例如 /cgi-bin/token；推送消息为 message/消息类型
+	AppID    string        // 账号
+	Start    time.Time     // 开始时间
+	Latency  time.Duration // 耗时，After中有效
+	ErrCode  int64         // 微信返回的errcode，After中有效
+	MchCode  string        // 商户平台返回的错误码，return_code为FAIL时为FAIL，result_code为FAIL时为err_code，After中有效
+	Retries  int           // 第几次重试，0表示第一次请求
+	Err      error         // 错误（已脱敏），After中有效
+}
+
+//Interceptor 在每次调用微信接口、处理推送消息前后调用，用于统计、链路追踪
+//
+//Before返回的ctx会传给After，可以在其中保存span等信息
+type Interceptor interface {
+	Before(ctx context.Context, info *CallInfo) context.Context
+	After(ctx context.Context, info *CallInfo)
+}
+
+type retriesKey struct{}
+
+//outboundKey 标记ctx中的请求已经由调用方调用了StartCall，interceptTransport不再重复记录
+type outboundKey struct{}
+
+//WithRetries 标记ctx中的请求为第n次重试
+func WithRetries(ctx context.Context, n int) context.Context {
+	return context.WithValue(ctx, retriesKey{}, n)
+}
+
+//Retries 返回ctx中标记的重试次数
+func Retries(ctx context.Context) int {
+	n, _ := ctx.Value(retriesKey{}).(int)
+	return n
+}
+
+//StartCall 调用Interceptors的Before，没有设置Interceptors时返回的info为nil
+func (cfg *Config) StartCall(ctx context.Context, kind, endpoint string) (context.Context, *CallInfo) {
+	if len(cfg.Interceptors) == 0 {
+		return ctx, nil
+	}
+	if i := strings.IndexAny(endpoint, "?#"); i >= 0 {
+		endpoint = endpoint[:i]
+	}
+	info := &CallInfo{
+		Kind:     kind,
+		Endpoint: endpoint,
+		AppID:    cfg.AppID,
+		Start:    time.Now(),
+		Retries:  Retries(ctx),
+	}
+	if kind == CallOutbound {
+		ctx = context.WithValue(ctx, outboundKey{}, true)
+	}
+	for _, interceptor := range cfg.Interceptors {
+		ctx = interceptor.Before(ctx, info)
+	}
+	return ctx, info
+}
+
+//EndCall 记录结果并按相反的顺序调用Interceptors的After
+func (cfg *Config) EndCall(ctx context.Context, info *CallInfo, err error) {
+	if info == nil {
+		return
+	}
+	info.Latency = time.Since(info.Start)
+	info.Err = util.RedactError(err)
+	info.ErrCode = util.ErrCodeOf(err)
+	for i := len(cfg.Interceptors) - 1; i >= 0; i-- {
+		cfg.Interceptors[i].After(ctx, info)
+	}
+}
+
+//interceptTransport 对所有出站请求调用Interceptors，json响应会解析其中的errcode，
+//xml等需要调用方解析的响应由调用方自己调用StartCall、EndCall
+type interceptTransport struct {
+	cfg  *Config
+	next http.RoundTripper
+}
+
+func (t *interceptTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Context().Value(outboundKey{}) != nil {
+		return t.next.RoundTrip(req)
+	}
+	ctx, info := t.cfg.StartCall(req.Context(), CallOutbound, req.URL.Path)
+	resp, err := t.next.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		t.cfg.EndCall(ctx, info, err)
+		return nil, err
+	}
+	//errcode由调用方自己处理，这里只用于统计
+	apiErr, err := checkResponse(req, resp)
+	if err != nil {
+		t.cfg.EndCall(ctx, info, err)
+		return nil, err
+	}
+	t.cfg.EndCall(ctx, info, apiErr)
+	return resp, nil
+}
+
+//checkResponse 解析json响应中的errcode，读取后放回Body
+func checkResponse(req *http.Request, resp *http.Response) (apiErr, err error) {
+	contentType := resp.Header.Get("Content-Type")
+	if !strings.Contains(contentType, "json") && !strings.HasPrefix(contentType, "text/plain") {
+		return nil, nil
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+	if apiErr = util.CheckAPIError(req.URL.Path, body); apiErr == util.ErrUnmarshall {
+		apiErr = nil
+	}
+	return
+}
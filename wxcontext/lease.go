@@ -0,0 +1,148 @@
+package wxcontext
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/MrCHI/gowechat/cache"
+	"github.com/MrCHI/gowechat/util"
+)
+
+//TokenLeaseWaitInterval 其他进程正在刷新token时，检查结果的间隔
+var TokenLeaseWaitInterval = 100 * time.Millisecond
+
+//FetchTokenFunc 从微信服务器获取token，返回token和在缓存中保存的时长
+type FetchTokenFunc func(reqCtx context.Context) (token string, expires time.Duration, err error)
+
+//RefreshToken 从缓存读取token，没有时刷新并写入缓存
+//
+//Cache实现了cache.Locker时，共用同一个缓存的多个进程通过租约协调，同一时间只有一个进程请求微信服务器，
+//其他进程等待它写入的结果。租约带有唯一的fencing标识，持有者写入前会检查租约仍然属于自己
+//（Cache实现cache.Fencer时检查和写入是原子的），
+//租约过期（请求超时）后被其他进程接手时，迟到的结果不会覆盖新的token。
+//
+//调用方需要自己保证进程内的互斥（见 TokenLock）
+func (ctx *Context) RefreshToken(reqCtx context.Context, cacheKey string, fetch FetchTokenFunc) (token string, err error) {
+	if token = cache.GetString(ctx.Cache, cacheKey); token != "" {
+		return
+	}
+	locker, ok := ctx.Cache.(cache.Locker)
+	if !ok {
+		var expires time.Duration
+		if token, expires, err = fetch(reqCtx); err != nil {
+			return
+		}
+		err = ctx.Cache.Put(cacheKey, token, expires)
+		return
+	}
+
+	leaseKey := cacheKey + "_lease"
+	fence := fmt.Sprintf("%d-%d-%s", os.Getpid(), time.Now().UnixNano(), util.RandomStr(8))
+	for {
+		var acquired bool
+		acquired, err = locker.PutIfAbsent(leaseKey, fence, ctx.tokenLeaseTimeout())
+		if err != nil {
+			return
+		}
+		if acquired {
+			return ctx.refreshWithLease(reqCtx, locker, cacheKey, leaseKey, fence, fetch)
+		}
+
+		//其他进程持有租约，等待结果或租约释放
+		for ctx.Cache.IsExist(leaseKey) {
+			if token = cache.GetString(ctx.Cache, cacheKey); token != "" {
+				return
+			}
+			select {
+			case <-reqCtx.Done():
+				return "", reqCtx.Err()
+			case <-time.After(TokenLeaseWaitInterval):
+			}
+		}
+		if token = cache.GetString(ctx.Cache, cacheKey); token != "" {
+			return
+		}
+	}
+}
+
+func (ctx *Context) refreshWithLease(reqCtx context.Context, locker cache.Locker, cacheKey, leaseKey, fence string, fetch FetchTokenFunc) (token string, err error) {
+	defer locker.CompareAndDelete(leaseKey, fence)
+
+	//获得租约前可能刚有进程写入
+	if token = cache.GetString(ctx.Cache, cacheKey); token != "" {
+		return
+	}
+	var expires time.Duration
+	if token, expires, err = fetch(reqCtx); err != nil {
+		return
+	}
+	//租约已被其他进程接手时不写入，以它的结果为准
+	_, err = ctx.putIfHeld(leaseKey, fence, cacheKey, token, expires)
+	return
+}
+
+//putIfHeld 租约仍然属于自己时写入，Cache没有实现cache.Fencer时检查和写入不是原子的
+func (ctx *Context) putIfHeld(leaseKey, fence, key, val string, timeout time.Duration) (bool, error) {
+	if fencer, ok := ctx.Cache.(cache.Fencer); ok {
+		return fencer.PutIfHeld(leaseKey, fence, key, val, timeout)
+	}
+	if cache.GetString(ctx.Cache, leaseKey) != fence {
+		return false, nil
+	}
+	return true, ctx.Cache.Put(key, val, timeout)
+}
+
+//tokenLeaseTimeout 租约时长，需要覆盖一次请求的超时时间
+func (ctx *Context) tokenLeaseTimeout() time.Duration {
+	return ctx.httpTimeout() + 5*time.Second
+}
+
+//RenewToken 主动刷新缓存中的token（即使还没有过期），返回距离下次刷新的时间，供 Refresher 使用
+//
+//Cache实现了cache.Locker时，多个进程中只有一个真正请求微信服务器，其余进程读取它记录的下次刷新时间
+func (ctx *Context) RenewToken(reqCtx context.Context, cacheKey string, fetch FetchTokenFunc) (next time.Duration, err error) {
+	locker, ok := ctx.Cache.(cache.Locker)
+	if !ok {
+		var token string
+		var expires time.Duration
+		if token, expires, err = fetch(reqCtx); err != nil {
+			return
+		}
+		return renewAfter(expires), ctx.Cache.Put(cacheKey, token, expires)
+	}
+
+	//其他进程刚刷新过
+	renewKey := cacheKey + "_renew_at"
+	if due, e := time.Parse(time.RFC3339Nano, cache.GetString(ctx.Cache, renewKey)); e == nil && time.Until(due) > 0 {
+		return time.Until(due), nil
+	}
+
+	leaseKey := cacheKey + "_lease"
+	fence := fmt.Sprintf("%d-%d-%s", os.Getpid(), time.Now().UnixNano(), util.RandomStr(8))
+	var acquired bool
+	if acquired, err = locker.PutIfAbsent(leaseKey, fence, ctx.tokenLeaseTimeout()); err != nil || !acquired {
+		//其他进程正在刷新，稍后读取它的结果
+		return time.Second, err
+	}
+	defer locker.CompareAndDelete(leaseKey, fence)
+
+	var token string
+	var expires time.Duration
+	if token, expires, err = fetch(reqCtx); err != nil {
+		return
+	}
+	var written bool
+	if written, err = ctx.putIfHeld(leaseKey, fence, cacheKey, token, expires); err != nil || !written {
+		return time.Second, err
+	}
+	next = renewAfter(expires)
+	err = ctx.Cache.Put(renewKey, time.Now().Add(next).Format(time.RFC3339Nano), next)
+	return
+}
+
+//renewAfter 在缓存过期前刷新，留出失败重试的时间
+func renewAfter(expires time.Duration) time.Duration {
+	return expires * 3 / 4
+}
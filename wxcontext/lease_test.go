@@ -0,0 +1,83 @@
+package wxcontext
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/MrCHI/gowechat/cache"
+)
+
+func TestRefreshTokenSharedCache(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&calls, 1)
+		time.Sleep(50 * time.Millisecond)
+		fmt.Fprintf(w, `{"access_token":"token-%d","expires_in":7200}`, n)
+	}))
+	defer srv.Close()
+
+	//多个Context共用一个缓存，模拟共用AppID的多个副本
+	store := cache.NewMemory(0)
+	var wg sync.WaitGroup
+	tokens := make([]string, 8)
+	for i := range tokens {
+		ctx := &Context{Config: &Config{
+			AppID:     "appid",
+			AppSecret: "secret",
+			Cache:     store,
+			Endpoint:  AllEndpoints(srv.URL),
+		}}
+		ctx.SetAccessTokenLocker(NewTokenLock())
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			token, err := ctx.GetAccessToken()
+			if err != nil {
+				t.Error(err)
+			}
+			tokens[i] = token
+		}(i)
+	}
+	wg.Wait()
+
+	if calls != 1 {
+		t.Errorf("token refreshed %d times, want 1", calls)
+	}
+	for _, token := range tokens {
+		if token != "token-1" {
+			t.Errorf("got token %q, want token-1", token)
+		}
+	}
+}
+
+//lockerOnly 只实现Cache和Locker，检查租约和写入分两步
+type lockerOnly struct {
+	cache.Cache
+	cache.Locker
+}
+
+func TestRefreshTokenStaleLease(t *testing.T) {
+	m := cache.NewMemory(0)
+	for _, store := range []cache.Cache{cache.NewMemory(0), lockerOnly{m, m}} {
+		ctx := &Context{Config: &Config{Cache: store}}
+		//请求超时，租约过期后被其他进程接手并写入了新的token
+		token, err := ctx.RefreshToken(context.Background(), "token", func(context.Context) (string, time.Duration, error) {
+			store.Delete("token_lease")
+			store.(cache.Locker).PutIfAbsent("token_lease", "other", time.Minute)
+			store.Put("token", "new", time.Minute)
+			return "stale", time.Minute, nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := cache.GetString(store, "token"); got != "new" || token != "stale" {
+			t.Errorf("%T: cached token %q, returned %q; the stale token should not overwrite the new one", store, got, token)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package wxcontext
+
+import (
+	"context"
+	"sync"
+)
+
+//TokenLock 获取token时使用的互斥锁（一个appID一个），等待时可以被context取消
+type TokenLock struct {
+	ch chan struct{}
+	//mu 通过已废弃的 SetAccessTokenLock、SetJsAPITicketLock 设置的锁，等待时不能取消
+	mu *sync.RWMutex
+}
+
+//tokenLocks 按key共享的锁，同一个AppID的多个Context使用同一把锁
+var tokenLocks sync.Map
+
+//NewTokenLock 实例化
+func NewTokenLock() *TokenLock {
+	return &TokenLock{ch: make(chan struct{}, 1)}
+}
+
+//Lock 加锁，ctx结束时放弃等待并返回ctx.Err()
+func (l *TokenLock) Lock(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if l.mu != nil {
+		l.mu.Lock()
+		return nil
+	}
+	select {
+	case l.ch <- struct{}{}:
+		return nil
+	default:
+	}
+	select {
+	case l.ch <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+//Unlock 解锁
+func (l *TokenLock) Unlock() {
+	if l.mu != nil {
+		l.mu.Unlock()
+		return
+	}
+	<-l.ch
+}
+
+//SharedTokenLock 返回key对应的锁，同一个key总是返回同一把锁
+//
+//key一般为 类型+AppID，例如 "access_token_"+appID
+func SharedTokenLock(key string) *TokenLock {
+	if l, ok := tokenLocks.Load(key); ok {
+		return l.(*TokenLock)
+	}
+	l, _ := tokenLocks.LoadOrStore(key, NewTokenLock())
+	return l.(*TokenLock)
+}
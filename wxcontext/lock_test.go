@@ -0,0 +1,68 @@
+package wxcontext
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestTokenLockCancel(t *testing.T) {
+	l := NewTokenLock()
+	if err := l.Lock(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := l.Lock(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("waiting on a held lock should stop with the context, got %v", err)
+	}
+	l.Unlock()
+	if err := l.Lock(ctx); err == nil {
+		t.Error("an expired context should not take the lock")
+	}
+	if err := l.Lock(context.Background()); err != nil {
+		t.Errorf("lock should be free after Unlock, got %v", err)
+	}
+	if SharedTokenLock("access_token_a") != SharedTokenLock("access_token_a") || SharedTokenLock("access_token_a") == SharedTokenLock("access_token_b") {
+		t.Error("SharedTokenLock should return one lock per key")
+	}
+}
+
+func TestGetAccessTokenContextCanceled(t *testing.T) {
+	ctx := &Context{Config: &Config{AppID: "appid"}}
+	ctx.SetAccessTokenLocker(NewTokenLock())
+	ctx.accessTokenLock.Lock(context.Background())
+	defer ctx.accessTokenLock.Unlock()
+
+	reqCtx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := ctx.GetAccessTokenContext(reqCtx); !errors.Is(err, context.Canceled) {
+		t.Errorf("want context.Canceled while another caller holds the lock, got %v", err)
+	}
+}
+
+func TestDeprecatedRWMutexLocks(t *testing.T) {
+	ctx := &Context{Config: &Config{}}
+	mu := new(sync.RWMutex)
+	ctx.SetJsAPITicketLock(mu)
+	if ctx.GetJsAPITicketLock() != mu {
+		t.Error("GetJsAPITicketLock should return the mutex passed to SetJsAPITicketLock")
+	}
+	if err := ctx.GetJsAPITicketLocker().Lock(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if mu.TryLock() {
+		t.Error("TokenLock should hold the wrapped mutex")
+	}
+	ctx.GetJsAPITicketLocker().Unlock()
+	if !mu.TryLock() {
+		t.Error("TokenLock should release the wrapped mutex")
+	}
+
+	ctx.SetJsAPITicketLocker(NewTokenLock())
+	if ctx.GetJsAPITicketLock() != nil {
+		t.Error("GetJsAPITicketLock should be nil for a TokenLock")
+	}
+}
@@ -0,0 +1,116 @@
+package wxcontext
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/MrCHI/gowechat/util"
+)
+
+//LogLevel 日志级别
+type LogLevel int
+
+const (
+	//LevelDebug 调试信息，例如每次请求
+	LevelDebug LogLevel = iota
+	//LevelInfo 正常的状态变化，例如刷新token
+	LevelInfo
+	//LevelWarn 可以自动恢复的错误，例如token失效后重试
+	LevelWarn
+	//LevelError 需要关注的错误
+	LevelError
+)
+
+var levelNames = [...]string{"DEBUG", "INFO", "WARN", "ERROR"}
+
+func (l LogLevel) String() string {
+	if l >= 0 && int(l) < len(levelNames) {
+		return levelNames[l]
+	}
+	return fmt.Sprintf("LEVEL(%d)", int(l))
+}
+
+//Logger 日志接口，keyvals为交替出现的key、value
+//
+//SDK传入的keyvals已经脱敏，ctx中带有请求ID时会追加 request_id
+type Logger interface {
+	Log(ctx context.Context, level LogLevel, msg string, keyvals ...interface{})
+}
+
+//LoggerFunc 函数形式的Logger
+type LoggerFunc func(ctx context.Context, level LogLevel, msg string, keyvals ...interface{})
+
+//Log 实现Logger
+func (f LoggerFunc) Log(ctx context.Context, level LogLevel, msg string, keyvals ...interface{}) {
+	f(ctx, level, msg, keyvals...)
+}
+
+//NewStdLogger 输出到标准库的log.Logger，低于minLevel的日志忽略，格式为 "LEVEL msg key=value ..."
+func NewStdLogger(l *log.Logger, minLevel LogLevel) Logger {
+	return LoggerFunc(func(ctx context.Context, level LogLevel, msg string, keyvals ...interface{}) {
+		if level < minLevel {
+			return
+		}
+		var b strings.Builder
+		b.WriteString(level.String())
+		b.WriteByte(' ')
+		b.WriteString(msg)
+		for i := 0; i+1 < len(keyvals); i += 2 {
+			fmt.Fprintf(&b, " %v=%v", keyvals[i], keyvals[i+1])
+		}
+		l.Print(b.String())
+	})
+}
+
+type requestIDKey struct{}
+
+//WithRequestID 在ctx中带上请求ID，之后的日志都会输出该ID
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+//RequestID 返回ctx中的请求ID
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+//Log 输出日志，value按key名脱敏（token、ticket、secret、key、sign、code等），见 util.RedactString
+func (cfg *Config) Log(ctx context.Context, level LogLevel, msg string, keyvals ...interface{}) {
+	if cfg.Logger == nil {
+		return
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	fields := make([]interface{}, 0, len(keyvals)+4)
+	if cfg.AppID != "" {
+		fields = append(fields, "appid", cfg.AppID)
+	}
+	for i := 0; i+1 < len(keyvals); i += 2 {
+		key := fmt.Sprint(keyvals[i])
+		fields = append(fields, key, redactField(key, keyvals[i+1]))
+	}
+	if id := RequestID(ctx); id != "" {
+		fields = append(fields, "request_id", id)
+	}
+	cfg.Logger.Log(ctx, level, util.RedactString(msg), fields...)
+}
+
+//redactField 敏感字段只保留前几个字符，其他字符串、错误中的敏感参数同样隐藏
+func redactField(key string, val interface{}) interface{} {
+	if util.IsSensitiveKey(key) {
+		return util.RedactValue(fmt.Sprint(val))
+	}
+	switch v := val.(type) {
+	case string:
+		return util.RedactString(v)
+	case error:
+		return util.RedactError(v)
+	case fmt.Stringer:
+		return util.RedactString(v.String())
+	}
+	return val
+}
@@ -0,0 +1,48 @@
+package wxcontext
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"testing"
+)
+
+func TestStdLoggerLevel(t *testing.T) {
+	var out bytes.Buffer
+	logger := NewStdLogger(log.New(&out, "", 0), LevelInfo)
+	logger.Log(context.Background(), LevelDebug, "hidden")
+	logger.Log(context.Background(), LevelWarn, "shown", "a", 1, "b", "x")
+	if got := out.String(); got != "WARN shown a=1 b=x\n" {
+		t.Errorf("unexpected output %q", got)
+	}
+	if LogLevel(9).String() != "LEVEL(9)" {
+		t.Errorf("unexpected level name %s", LogLevel(9))
+	}
+}
+
+func TestLogRedact(t *testing.T) {
+	const secret = "secret0123456789"
+	var out bytes.Buffer
+	cfg := &Config{AppID: "wx1", Logger: NewStdLogger(log.New(&out, "", 0), LevelDebug)}
+	ctx := WithRequestID(context.Background(), "req-1")
+
+	cfg.Log(ctx, LevelDebug, "request msg",
+		"access_token", secret,
+		"url", "https://api.weixin.qq.com/cgi-bin/token?appid=wx1&secret="+secret,
+		"body", "<xml><Ticket><![CDATA["+secret+"]]></Ticket><Content><![CDATA[hello]]></Content></xml>",
+		"error", errors.New(`{"component_access_token": "`+secret+`"}`))
+	got := out.String()
+	if strings.Contains(got, secret) {
+		t.Errorf("secret leaked in %q", got)
+	}
+	for _, keep := range []string{"DEBUG request msg appid=wx1 ", "access_token=sec***", "<Content><![CDATA[hello]]></Content>", "request_id=req-1"} {
+		if !strings.Contains(got, keep) {
+			t.Errorf("%q missing in %q", keep, got)
+		}
+	}
+
+	//没有设置Logger时不输出
+	(&Config{}).Log(ctx, LevelError, "ignored")
+}
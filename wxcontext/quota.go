@@ -0,0 +1,142 @@
+package wxcontext
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/MrCHI/gowechat/cache"
+	"github.com/MrCHI/gowechat/util"
+)
+
+//DefaultQuotaWarnRatio 调用次数达到上限的该比例时告警
+const DefaultQuotaWarnRatio = 0.8
+
+//DefaultQuotaLimits 常用接口的每日调用上限，参考官方文档，实际以 get_quota 接口返回的为准
+var DefaultQuotaLimits = map[string]int64{
+	"/cgi-bin/token":                             2000,
+	"/cgi-bin/stable_token":                      10000,
+	"/cgi-bin/ticket/getticket":                  1000000,
+	"/cgi-bin/menu/create":                       1000,
+	"/cgi-bin/menu/get":                          10000,
+	"/cgi-bin/menu/delete":                       1000,
+	"/cgi-bin/menu/addconditional":               2000,
+	"/cgi-bin/menu/delconditional":               2000,
+	"/cgi-bin/menu/trymatch":                     20000,
+	"/cgi-bin/get_current_selfmenu_info":         10000,
+	"/cgi-bin/message/template/send":             100000,
+	"/cgi-bin/template/get_all_private_template": 100000,
+	"/cgi-bin/qrcode/create":                     100000,
+	"/cgi-bin/user/info":                         5000000,
+	"/cgi-bin/user/get":                          1000,
+	"/cgi-bin/media/upload":                      100000,
+	"/cgi-bin/media/uploadimg":                   5000,
+	"/cgi-bin/material/add_material":             5000,
+	"/cgi-bin/material/add_news":                 5000,
+	"/cgi-bin/material/del_material":             5000,
+	"/cgi-bin/clear_quota":                       10,
+}
+
+//quotaZone 微信按北京时间每天0点重置调用次数
+var quotaZone = time.FixedZone("CST", 8*3600)
+
+//QuotaUsage 接口当天的调用情况，Limit<=0 表示不知道上限
+type QuotaUsage struct {
+	Endpoint string
+	Used     int64
+	Limit    int64
+	Remain   int64
+}
+
+//CountCall 记录一次接口调用（按账号、接口、天计数，保存在Cache中），达到告警比例或上限时告警
+//
+//只在设置了 CountQuota 时计数，不知道上限的接口不计数；err为45009（接口调用超过每日上限）时计数直接置为上限
+func (ctx *Context) CountCall(reqCtx context.Context, endpoint string, err error) {
+	if !ctx.CountQuota {
+		return
+	}
+	endpoint = endpointPath(endpoint)
+	limit := ctx.quotaLimit(endpoint)
+
+	if util.ErrCodeOf(err) == util.ErrCodeAPIDailyQuotaExceeded {
+		if limit > 0 {
+			ctx.Cache.Put(ctx.quotaCacheKey(endpoint, time.Now()), strconv.FormatInt(limit, 10), 25*time.Hour)
+		}
+		ctx.warnQuota(reqCtx, endpoint, limit, limit)
+		return
+	}
+	if limit <= 0 {
+		return
+	}
+
+	used, e := cache.Increase(ctx.Cache, ctx.quotaCacheKey(endpoint, time.Now()), 25*time.Hour)
+	if e != nil {
+		return
+	}
+	ratio := ctx.QuotaWarnRatio
+	if ratio <= 0 {
+		ratio = DefaultQuotaWarnRatio
+	}
+	//只在越过阈值和达到上限时各告警一次
+	if used == int64(float64(limit)*ratio) || used == limit {
+		ctx.warnQuota(reqCtx, endpoint, used, limit)
+	}
+}
+
+//GetQuotaUsage 返回接口当天的调用情况（本SDK记录的次数，不包括其他程序的调用），没有设置 CountQuota 时Used为0
+func (ctx *Context) GetQuotaUsage(endpoint string) QuotaUsage {
+	endpoint = endpointPath(endpoint)
+	usage := QuotaUsage{
+		Endpoint: endpoint,
+		Used:     cache.GetInt(ctx.Cache, ctx.quotaCacheKey(endpoint, time.Now())),
+		Limit:    ctx.quotaLimit(endpoint),
+	}
+	if usage.Limit > 0 {
+		usage.Remain = usage.Limit - usage.Used
+		if usage.Remain < 0 {
+			usage.Remain = 0
+		}
+	}
+	return usage
+}
+
+//ResetQuotaUsage 清空当天所有接口的计数，调用clear_quota成功后使用
+func (ctx *Context) ResetQuotaUsage() error {
+	_, err := cache.Increase(ctx.Cache, ctx.quotaGenerationKey(time.Now()), 25*time.Hour)
+	return err
+}
+
+func (ctx *Context) warnQuota(reqCtx context.Context, endpoint string, used, limit int64) {
+	ctx.Log(reqCtx, LevelWarn, "接口调用次数接近每日上限", "endpoint", endpoint, "used", used, "limit", limit)
+	if ctx.OnQuotaWarning != nil {
+		ctx.OnQuotaWarning(ctx.AppID, endpoint, used, limit)
+	}
+}
+
+func (ctx *Context) quotaLimit(endpoint string) int64 {
+	if limit, ok := ctx.QuotaLimits[endpoint]; ok {
+		return limit
+	}
+	return DefaultQuotaLimits[endpoint]
+}
+
+//quotaCacheKey 计数的key，清零时增加generation，不需要逐个删除
+func (ctx *Context) quotaCacheKey(endpoint string, now time.Time) string {
+	generation := cache.GetInt(ctx.Cache, ctx.quotaGenerationKey(now))
+	return fmt.Sprintf("quota_%s_%s_%d_%s", ctx.AppID, now.In(quotaZone).Format("20060102"), generation, endpoint)
+}
+
+func (ctx *Context) quotaGenerationKey(now time.Time) string {
+	return fmt.Sprintf("quota_%s_%s_generation", ctx.AppID, now.In(quotaZone).Format("20060102"))
+}
+
+//endpointPath 去掉域名和参数，只保留接口路径
+func endpointPath(endpoint string) string {
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Path == "" {
+		return endpoint
+	}
+	return u.Path
+}
@@ -0,0 +1,101 @@
+package wxcontext
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/MrCHI/gowechat/cache"
+	"github.com/MrCHI/gowechat/util"
+)
+
+const menuCreateURL = "https://api.weixin.qq.com/cgi-bin/menu/create?access_token=token"
+
+func newQuotaContext(count bool, ratio float64, warned *[]int64) *Context {
+	return &Context{Config: &Config{
+		AppID:          "wx1",
+		Cache:          cache.NewMemory(time.Minute),
+		CountQuota:     count,
+		QuotaLimits:    map[string]int64{"/cgi-bin/menu/create": 10},
+		QuotaWarnRatio: ratio,
+		OnQuotaWarning: func(appID, endpoint string, used, limit int64) {
+			*warned = append(*warned, used)
+		},
+	}}
+}
+
+func TestCountCall(t *testing.T) {
+	tests := []struct {
+		name   string
+		count  bool
+		ratio  float64
+		calls  int
+		used   int64
+		warned []int64
+	}{
+		{name: "disabled", count: false, calls: 10, used: 0},
+		{name: "below", count: true, calls: 7, used: 7},
+		{name: "default ratio", count: true, calls: 11, used: 11, warned: []int64{8, 10}},
+		{name: "ratio", count: true, ratio: 0.5, calls: 6, used: 6, warned: []int64{5}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var warned []int64
+			ctx := newQuotaContext(tt.count, tt.ratio, &warned)
+			for i := 0; i < tt.calls; i++ {
+				ctx.CountCall(context.Background(), menuCreateURL, nil)
+			}
+			usage := ctx.GetQuotaUsage("/cgi-bin/menu/create")
+			if usage.Used != tt.used || usage.Limit != 10 {
+				t.Errorf("usage %+v, want used %d", usage, tt.used)
+			}
+			if len(warned) != len(tt.warned) {
+				t.Fatalf("warned at %v, want %v", warned, tt.warned)
+			}
+			for i := range warned {
+				if warned[i] != tt.warned[i] {
+					t.Errorf("warned at %v, want %v", warned, tt.warned)
+				}
+			}
+		})
+	}
+}
+
+func TestCountCallSkipsUnlimited(t *testing.T) {
+	var warned []int64
+	ctx := newQuotaContext(true, 0, &warned)
+	ctx.CountCall(context.Background(), "https://api.weixin.qq.com/cgi-bin/unknown/api", nil)
+	if usage := ctx.GetQuotaUsage("/cgi-bin/unknown/api"); usage.Used != 0 || usage.Limit != 0 {
+		t.Errorf("endpoint without limit should not be counted, got %+v", usage)
+	}
+}
+
+func TestCountCallQuotaExceeded(t *testing.T) {
+	var warned []int64
+	ctx := newQuotaContext(true, 0, &warned)
+	ctx.CountCall(context.Background(), menuCreateURL, &util.APIError{ErrCode: util.ErrCodeAPIDailyQuotaExceeded})
+	if usage := ctx.GetQuotaUsage("/cgi-bin/menu/create"); usage.Used != 10 || usage.Remain != 0 {
+		t.Errorf("usage %+v, want the limit", usage)
+	}
+	if len(warned) != 1 || warned[0] != 10 {
+		t.Errorf("warned at %v, want [10]", warned)
+	}
+}
+
+func TestResetQuotaUsage(t *testing.T) {
+	var warned []int64
+	ctx := newQuotaContext(true, 0, &warned)
+	for i := 0; i < 3; i++ {
+		ctx.CountCall(context.Background(), menuCreateURL, nil)
+	}
+	if err := ctx.ResetQuotaUsage(); err != nil {
+		t.Fatal(err)
+	}
+	if usage := ctx.GetQuotaUsage("/cgi-bin/menu/create"); usage.Used != 0 || usage.Remain != 10 {
+		t.Errorf("usage after reset %+v", usage)
+	}
+	ctx.CountCall(context.Background(), menuCreateURL, nil)
+	if usage := ctx.GetQuotaUsage("/cgi-bin/menu/create"); usage.Used != 1 {
+		t.Errorf("usage after reset and one call %+v", usage)
+	}
+}
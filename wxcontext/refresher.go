@@ -0,0 +1,154 @@
+package wxcontext
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+const (
+	//refreshMinBackoff 刷新失败后第一次重试的等待时间，之后每次翻倍
+	refreshMinBackoff = time.Second
+	//refreshMaxBackoff 重试等待时间的上限
+	refreshMaxBackoff = 5 * time.Minute
+	//refreshTimeout 单次刷新的超时
+	refreshTimeout = 2 * time.Minute
+)
+
+//RefreshFunc 刷新一次，返回距离下次刷新的时间
+type RefreshFunc func(ctx context.Context) (next time.Duration, err error)
+
+//RefreshTask 后台刷新的任务
+type RefreshTask struct {
+	Name    string
+	Refresh RefreshFunc
+}
+
+//RefreshStatus 任务状态
+type RefreshStatus struct {
+	Name        string
+	LastRefresh time.Time // 最近一次成功的时间
+	NextRefresh time.Time // 计划的下次刷新时间
+	LastError   error     // 最近一次失败的错误，成功后清空
+	Failures    int       // 连续失败次数
+}
+
+//Refresher 在token过期前主动刷新，避免业务请求遇到过期时才去获取
+//
+//下次刷新的时间加了±10%的随机抖动，失败后按指数退避重试
+type Refresher struct {
+	tasks []RefreshTask
+
+	mu      sync.Mutex
+	status  []RefreshStatus
+	cancel  context.CancelFunc
+	stopped *sync.WaitGroup
+}
+
+//NewRefresher 实例化，调用Start后开始运行
+func NewRefresher(tasks ...RefreshTask) *Refresher {
+	r := &Refresher{tasks: tasks, status: make([]RefreshStatus, len(tasks))}
+	for i, task := range tasks {
+		r.status[i].Name = task.Name
+	}
+	return r
+}
+
+//Start 每个任务启动一个goroutine，立即刷新一次；运行中重复调用无效，Stop之后可以再次启动
+func (r *Refresher) Start() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.cancel != nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	r.cancel = cancel
+	r.stopped = new(sync.WaitGroup)
+	for i := range r.tasks {
+		r.stopped.Add(1)
+		go r.run(ctx, r.stopped, i)
+	}
+}
+
+//Stop 停止所有任务，等待正在进行的刷新结束
+func (r *Refresher) Stop() {
+	r.mu.Lock()
+	cancel, stopped := r.cancel, r.stopped
+	r.cancel, r.stopped = nil, nil
+	r.mu.Unlock()
+	if cancel == nil {
+		return
+	}
+	cancel()
+	stopped.Wait()
+}
+
+//Status 返回所有任务的状态
+func (r *Refresher) Status() []RefreshStatus {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	status := make([]RefreshStatus, len(r.status))
+	copy(status, r.status)
+	return status
+}
+
+func (r *Refresher) run(ctx context.Context, stopped *sync.WaitGroup, i int) {
+	defer stopped.Done()
+	task := r.tasks[i]
+	for {
+		reqCtx, cancel := context.WithTimeout(ctx, refreshTimeout)
+		next, err := task.Refresh(reqCtx)
+		cancel()
+		if ctx.Err() != nil {
+			return
+		}
+
+		r.mu.Lock()
+		status := &r.status[i]
+		if err != nil {
+			status.Failures++
+			status.LastError = err
+			next = backoff(status.Failures)
+		} else {
+			status.Failures = 0
+			status.LastError = nil
+			status.LastRefresh = time.Now()
+			next = jitter(next)
+		}
+		status.NextRefresh = time.Now().Add(next)
+		r.mu.Unlock()
+
+		timer := time.NewTimer(next)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+//backoff 第n次连续失败后的等待时间
+func backoff(failures int) time.Duration {
+	d := refreshMinBackoff
+	for i := 1; i < failures && d < refreshMaxBackoff; i++ {
+		d *= 2
+	}
+	if d > refreshMaxBackoff {
+		d = refreshMaxBackoff
+	}
+	return jitter(d)
+}
+
+//jitter 加上±10%的随机抖动，避免多个账号、多个进程同时刷新
+func jitter(d time.Duration) time.Duration {
+	if d <= 0 {
+		return refreshMinBackoff
+	}
+	delta := int64(d / 10)
+	if delta <= 0 {
+		return d
+	}
+	return d - time.Duration(delta) + time.Duration(rand.Int63n(2*delta))
+}
@@ -0,0 +1,111 @@
+package wxcontext
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/MrCHI/gowechat/cache"
+)
+
+func TestBackoffAndJitter(t *testing.T) {
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{4, 8 * time.Second},
+		{20, refreshMaxBackoff},
+	}
+	for _, tt := range tests {
+		for i := 0; i < 20; i++ {
+			if got := backoff(tt.failures); got < tt.want*9/10 || got > tt.want*11/10 {
+				t.Errorf("backoff(%d) = %v, want %v±10%%", tt.failures, got, tt.want)
+			}
+		}
+	}
+	if got := jitter(0); got != refreshMinBackoff {
+		t.Errorf("jitter(0) = %v, want %v", got, refreshMinBackoff)
+	}
+}
+
+//waitStatus 等待任务的状态满足ok
+func waitStatus(t *testing.T, r *Refresher, ok func(RefreshStatus) bool) RefreshStatus {
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		status := r.Status()[0]
+		if ok(status) {
+			return status
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("timeout, status %+v", status)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestRefresher(t *testing.T) {
+	var calls int32
+	fail := errors.New("fail")
+	r := NewRefresher(RefreshTask{Name: "token", Refresh: func(ctx context.Context) (time.Duration, error) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			return 0, fail
+		}
+		return time.Hour, nil
+	}})
+
+	r.Start()
+	r.Start()
+	status := waitStatus(t, r, func(s RefreshStatus) bool { return s.Failures == 1 })
+	if status.LastError != fail || time.Until(status.NextRefresh) > 2*time.Second {
+		t.Errorf("failed refresh should retry soon, status %+v", status)
+	}
+	r.Stop()
+	r.Stop()
+
+	//Stop之后可以再次启动
+	r.Start()
+	defer r.Stop()
+	status = waitStatus(t, r, func(s RefreshStatus) bool { return !s.LastRefresh.IsZero() })
+	if status.Failures != 0 || status.LastError != nil || time.Until(status.NextRefresh) < 50*time.Minute {
+		t.Errorf("unexpected status after success %+v", status)
+	}
+	if n := atomic.LoadInt32(&calls); n != 2 {
+		t.Errorf("refreshed %d times, want 2", n)
+	}
+}
+
+func TestRenewToken(t *testing.T) {
+	store := cache.NewMemory(0)
+	var calls int32
+	fetch := func(context.Context) (string, time.Duration, error) {
+		atomic.AddInt32(&calls, 1)
+		return "token", 2 * time.Hour, nil
+	}
+	for i := 0; i < 2; i++ {
+		//共用缓存的两个Context，只有第一个请求微信服务器
+		ctx := &Context{Config: &Config{Cache: store}}
+		next, err := ctx.RenewToken(context.Background(), "token", fetch)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if next < 89*time.Minute || next > 90*time.Minute {
+			t.Errorf("next refresh in %v, want 90m", next)
+		}
+	}
+	if calls != 1 || cache.GetString(store, "token") != "token" {
+		t.Errorf("fetched %d times, cached %q", calls, cache.GetString(store, "token"))
+	}
+
+	//没有实现Locker的缓存每次都刷新
+	plain := struct{ cache.Cache }{cache.NewMemory(0)}
+	ctx := &Context{Config: &Config{Cache: plain}}
+	ctx.RenewToken(context.Background(), "token", fetch)
+	ctx.RenewToken(context.Background(), "token", fetch)
+	if calls != 3 {
+		t.Errorf("fetched %d times, want 3", calls)
+	}
+}
@@ -10,8 +10,7 @@ var plainContentType = []string{"text/plain; charset=utf-8"}
 
 //Render render from bytes
 func (ctx *Context) Render(bytes []byte) {
-	//debug
-	// fmt.Println("response msg = ", string(bytes))
+	ctx.Log(ctx.RequestContext(), LevelDebug, "response msg", "body", string(bytes))
 	ctx.Writer.WriteHeader(200)
 	_, err := ctx.Writer.Write(bytes)
 	if err != nil {
@@ -0,0 +1,217 @@
+package wxcontext
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/MrCHI/gowechat/util"
+)
+
+//ConfigProblem 配置中的一项错误
+type ConfigProblem struct {
+	Field string
+	Msg   string
+}
+
+//ConfigError 配置校验失败，包含发现的所有问题
+type ConfigError struct {
+	Problems []ConfigProblem
+}
+
+func (e *ConfigError) Error() string {
+	lines := make([]string, 0, len(e.Problems)+1)
+	lines = append(lines, fmt.Sprintf("配置有%d处错误:", len(e.Problems)))
+	for _, p := range e.Problems {
+		lines = append(lines, fmt.Sprintf("  %s: %s", p.Field, p.Msg))
+	}
+	return strings.Join(lines, "\n")
+}
+
+//Add 添加一项错误
+func (e *ConfigError) Add(field, format string, args ...interface{}) {
+	e.Problems = append(e.Problems, ConfigProblem{Field: field, Msg: fmt.Sprintf(format, args...)})
+}
+
+//Merge 合并另一个校验结果，字段名加上prefix，err不是*ConfigError时整体作为一项错误
+func (e *ConfigError) Merge(prefix string, err error) {
+	if err == nil {
+		return
+	}
+	other, ok := err.(*ConfigError)
+	if !ok {
+		e.Add(prefix, "%v", err)
+		return
+	}
+	for _, p := range other.Problems {
+		if prefix != "" {
+			p.Field = prefix + "." + p.Field
+		}
+		e.Problems = append(e.Problems, p)
+	}
+}
+
+//Err 没有错误时返回nil
+func (e *ConfigError) Err() error {
+	if len(e.Problems) == 0 {
+		return nil
+	}
+	return e
+}
+
+//Validate 检查配置，公众平台参数总是检查，商户平台、开放平台参数在填写了任意一项时检查
+func (cfg *Config) Validate() error {
+	errs := new(ConfigError)
+	cfg.validateMp(errs)
+	if cfg.hasMch() {
+		cfg.validateMch(errs)
+	}
+	if cfg.hasOpenPlatform() {
+		cfg.validateOpenPlatform(errs)
+	}
+	cfg.validateCommon(errs)
+	return errs.Err()
+}
+
+//ValidateMp 检查公众平台参数
+func (cfg *Config) ValidateMp() error {
+	errs := new(ConfigError)
+	cfg.validateMp(errs)
+	cfg.validateCommon(errs)
+	return errs.Err()
+}
+
+//ValidateMch 检查公众平台和商户平台参数，包括证书能否加载
+func (cfg *Config) ValidateMch() error {
+	errs := new(ConfigError)
+	cfg.validateMp(errs)
+	cfg.validateMch(errs)
+	cfg.validateCommon(errs)
+	return errs.Err()
+}
+
+//ValidateOpenPlatform 检查开放平台参数
+func (cfg *Config) ValidateOpenPlatform() error {
+	errs := new(ConfigError)
+	cfg.validateOpenPlatform(errs)
+	cfg.validateCommon(errs)
+	return errs.Err()
+}
+
+func (cfg *Config) hasMch() bool {
+	return cfg.MchID != "" || cfg.MchAPIKey != "" ||
+		cfg.SslCertFilePath != "" || cfg.SslKeyFilePath != "" ||
+		cfg.SslCertContent != "" || cfg.SslKeyContent != ""
+}
+
+func (cfg *Config) hasOpenPlatform() bool {
+	return cfg.ComponentAppId != "" || cfg.ComponentAppSecret != "" ||
+		cfg.ComponentAppToken != "" || cfg.ComponentAppKey != ""
+}
+
+func (cfg *Config) validateMp(errs *ConfigError) {
+	required(errs, "AppID", cfg.AppID)
+	required(errs, "AppSecret", cfg.AppSecret)
+	required(errs, "Token", cfg.Token)
+	if cfg.EncodingAESKey != "" {
+		aesKey(errs, "EncodingAESKey", cfg.EncodingAESKey)
+	}
+	switch cfg.EncryptMode {
+	case "", EncryptModePlain:
+	case EncryptModeCompatible, EncryptModeSafe:
+		if cfg.EncodingAESKey == "" {
+			errs.Add("EncodingAESKey", "EncryptMode为%s时不能为空", cfg.EncryptMode)
+		}
+	default:
+		errs.Add("EncryptMode", "应为plain、compatible或safe，实际为%q", cfg.EncryptMode)
+	}
+	if cfg.MsgDedupWindow > 0 && cfg.MsgDedupWindow < 15*time.Second {
+		errs.Add("MsgDedupWindow", "应不少于15秒（微信的3次重试），实际为%v", cfg.MsgDedupWindow)
+	}
+	if cfg.MsgReplyTimeout >= 5*time.Second {
+		errs.Add("MsgReplyTimeout", "应小于5秒（微信等待回复的时间），实际为%v", cfg.MsgReplyTimeout)
+	}
+	if cfg.OriginalID != "" && !strings.HasPrefix(cfg.OriginalID, "gh_") {
+		errs.Add("OriginalID", "应以gh_开头")
+	}
+}
+
+func (cfg *Config) validateMch(errs *ConfigError) {
+	required(errs, "MchID", cfg.MchID)
+	required(errs, "MchAPIKey", cfg.MchAPIKey)
+	if cfg.MchAPIKey != "" && len(cfg.MchAPIKey) != 32 {
+		errs.Add("MchAPIKey", "长度应为32，实际为%d", len(cfg.MchAPIKey))
+	}
+
+	var err error
+	switch {
+	case cfg.SslCertContent != "" && cfg.SslKeyContent != "":
+		_, err = tls.X509KeyPair([]byte(cfg.SslCertContent), []byte(cfg.SslKeyContent))
+	case cfg.SslCertFilePath != "" && cfg.SslKeyFilePath != "":
+		_, err = tls.LoadX509KeyPair(cfg.SslCertFilePath, cfg.SslKeyFilePath)
+	default:
+		if cfg.SslCertFilePath == "" && cfg.SslCertContent == "" {
+			errs.Add("SslCert", "不能为空，需要SslCertFilePath或SslCertContent")
+		}
+		if cfg.SslKeyFilePath == "" && cfg.SslKeyContent == "" {
+			errs.Add("SslKey", "不能为空，需要SslKeyFilePath或SslKeyContent")
+		}
+		return
+	}
+	if err != nil {
+		errs.Add("SslCert", "证书加载失败: %v", err)
+	}
+}
+
+func (cfg *Config) validateOpenPlatform(errs *ConfigError) {
+	required(errs, "ComponentAppId", cfg.ComponentAppId)
+	required(errs, "ComponentAppSecret", cfg.ComponentAppSecret)
+	required(errs, "ComponentAppToken", cfg.ComponentAppToken)
+	required(errs, "ComponentAppKey", cfg.ComponentAppKey)
+	if cfg.ComponentAppKey != "" {
+		aesKey(errs, "ComponentAppKey", cfg.ComponentAppKey)
+	}
+}
+
+func (cfg *Config) validateCommon(errs *ConfigError) {
+	if cfg.HTTPTimeout < 0 {
+		errs.Add("HTTPTimeout", "不能为负数")
+	}
+	if cfg.QuotaWarnRatio < 0 || cfg.QuotaWarnRatio > 1 {
+		errs.Add("QuotaWarnRatio", "应在0到1之间，实际为%v", cfg.QuotaWarnRatio)
+	}
+	if cfg.HTTPProxy != "" {
+		if u, err := url.Parse(cfg.HTTPProxy); err != nil || u.Host == "" {
+			errs.Add("HTTPProxy", "不是有效的代理地址: %q", util.RedactURL(cfg.HTTPProxy))
+		}
+	}
+	endpoints := make([]string, 0, len(cfg.QuotaLimits))
+	for endpoint := range cfg.QuotaLimits {
+		endpoints = append(endpoints, endpoint)
+	}
+	sort.Strings(endpoints)
+	for _, endpoint := range endpoints {
+		if cfg.QuotaLimits[endpoint] <= 0 {
+			errs.Add("QuotaLimits["+endpoint+"]", "应大于0")
+		}
+	}
+}
+
+func required(errs *ConfigError, field, val string) {
+	if val == "" {
+		errs.Add(field, "不能为空")
+	}
+}
+
+func aesKey(errs *ConfigError, field, val string) {
+	if len(val) != 43 {
+		errs.Add(field, "长度应为43，实际为%d", len(val))
+		return
+	}
+	if err := util.CheckAESKey(val); err != nil {
+		errs.Add(field, "无效: %v", err)
+	}
+}
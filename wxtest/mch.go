@@ -0,0 +1,224 @@
+package wxtest
+
+import (
+	"bytes"
+	"net/http"
+	"strconv"
+
+	"github.com/MrCHI/gowechat/mch/base"
+	"github.com/MrCHI/gowechat/util"
+)
+
+//订单状态
+const (
+	TradeStateNotPay  = "NOTPAY"
+	TradeStateSuccess = "SUCCESS"
+	TradeStateRefund  = "REFUND"
+	TradeStateClosed  = "CLOSED"
+)
+
+//Order 统一下单创建的订单
+type Order struct {
+	OutTradeNo    string
+	Body          string
+	TotalFee      int64
+	TradeType     string
+	OpenID        string
+	NotifyURL     string
+	PrepayID      string
+	TransactionID string
+	TradeState    string
+	RefundFee     int64
+}
+
+func (s *Server) registerMch(mux *http.ServeMux) {
+	mux.HandleFunc("/pay/unifiedorder", s.withSign(s.handleUnifiedOrder))
+	mux.HandleFunc("/pay/orderquery", s.withSign(s.handleOrderQuery))
+	mux.HandleFunc("/secapi/pay/refund", s.withSign(s.handleRefund))
+}
+
+//Order 按商户订单号查询订单
+func (s *Server) Order(outTradeNo string) (Order, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	order, ok := s.orders[outTradeNo]
+	if !ok {
+		return Order{}, false
+	}
+	return *order, true
+}
+
+//PayOrder 模拟用户完成支付，返回的通知内容可以用 PushPayNotify 推送
+func (s *Server) PayOrder(outTradeNo string) (notify map[string]string, ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	order, ok := s.orders[outTradeNo]
+	if !ok || order.TradeState != TradeStateNotPay {
+		return nil, false
+	}
+	order.TradeState = TradeStateSuccess
+	order.TransactionID = "4200000" + strconv.FormatInt(s.nextID(), 10)
+	return s.signed(map[string]string{
+		"return_code":    base.ReturnCodeSuccess,
+		"result_code":    base.ResultCodeSuccess,
+		"appid":          s.AppID,
+		"mch_id":         s.MchID,
+		"nonce_str":      util.RandomStr(16),
+		"openid":         order.OpenID,
+		"trade_type":     order.TradeType,
+		"total_fee":      strconv.FormatInt(order.TotalFee, 10),
+		"transaction_id": order.TransactionID,
+		"out_trade_no":   order.OutTradeNo,
+		"time_end":       "20200101000000",
+	}), true
+}
+
+//withSign 解析xml请求并校验appid、mch_id和签名
+func (s *Server) withSign(h func(w http.ResponseWriter, req map[string]string)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		req, err := base.ParseXMLToMap(r.Body)
+		switch {
+		case err != nil:
+			s.writeXML(w, map[string]string{"return_code": base.ReturnCodeFail, "return_msg": "XML格式错误"})
+		case req["appid"] != s.AppID:
+			s.writeXML(w, map[string]string{"return_code": base.ReturnCodeFail, "return_msg": "appid不存在"})
+		case req["mch_id"] != s.MchID:
+			s.writeXML(w, map[string]string{"return_code": base.ReturnCodeFail, "return_msg": "商户号mch_id与appid不匹配"})
+		case req["sign"] != base.Sign(req, s.MchAPIKey, nil):
+			s.writeXML(w, map[string]string{"return_code": base.ReturnCodeFail, "return_msg": "签名错误"})
+		default:
+			h(w, req)
+		}
+	}
+}
+
+func (s *Server) handleUnifiedOrder(w http.ResponseWriter, req map[string]string) {
+	totalFee, _ := strconv.ParseInt(req["total_fee"], 10, 64)
+	for _, field := range []string{"body", "out_trade_no", "spbill_create_ip", "notify_url", "trade_type"} {
+		if req[field] == "" {
+			s.writeResult(w, "PARAM_ERROR", "缺少参数"+field, nil)
+			return
+		}
+	}
+	if totalFee <= 0 {
+		s.writeResult(w, "PARAM_ERROR", "total_fee无效", nil)
+		return
+	}
+	if req["trade_type"] == "JSAPI" && req["openid"] == "" {
+		s.writeResult(w, "PARAM_ERROR", "JSAPI支付必须传openid", nil)
+		return
+	}
+
+	s.mu.Lock()
+	if _, ok := s.orders[req["out_trade_no"]]; ok {
+		s.mu.Unlock()
+		s.writeResult(w, "OUT_TRADE_NO_USED", "商户订单号重复", nil)
+		return
+	}
+	order := &Order{
+		OutTradeNo: req["out_trade_no"],
+		Body:       req["body"],
+		TotalFee:   totalFee,
+		TradeType:  req["trade_type"],
+		OpenID:     req["openid"],
+		NotifyURL:  req["notify_url"],
+		PrepayID:   "wx" + strconv.FormatInt(s.nextID(), 10),
+		TradeState: TradeStateNotPay,
+	}
+	s.orders[order.OutTradeNo] = order
+	s.mu.Unlock()
+
+	resp := map[string]string{
+		"trade_type": order.TradeType,
+		"prepay_id":  order.PrepayID,
+	}
+	if order.TradeType == "NATIVE" {
+		resp["code_url"] = "weixin://wxpay/bizpayurl?pr=" + order.PrepayID
+	}
+	s.writeResult(w, "", "", resp)
+}
+
+func (s *Server) handleOrderQuery(w http.ResponseWriter, req map[string]string) {
+	order, ok := s.Order(req["out_trade_no"])
+	if !ok {
+		s.writeResult(w, "ORDERNOTEXIST", "此交易订单号不存在", nil)
+		return
+	}
+	s.writeResult(w, "", "", map[string]string{
+		"out_trade_no":   order.OutTradeNo,
+		"trade_state":    order.TradeState,
+		"trade_type":     order.TradeType,
+		"total_fee":      strconv.FormatInt(order.TotalFee, 10),
+		"transaction_id": order.TransactionID,
+		"openid":         order.OpenID,
+	})
+}
+
+func (s *Server) handleRefund(w http.ResponseWriter, req map[string]string) {
+	refundFee, _ := strconv.ParseInt(req["refund_fee"], 10, 64)
+	if req["out_refund_no"] == "" || refundFee <= 0 {
+		s.writeResult(w, "PARAM_ERROR", "缺少参数out_refund_no或refund_fee", nil)
+		return
+	}
+
+	s.mu.Lock()
+	order, ok := s.orders[req["out_trade_no"]]
+	var errCode, errMsg string
+	switch {
+	case !ok:
+		errCode, errMsg = "ORDERNOTEXIST", "此交易订单号不存在"
+	case order.TradeState != TradeStateSuccess && order.TradeState != TradeStateRefund:
+		errCode, errMsg = "TRADE_STATE_ERROR", "订单状态错误"
+	case order.RefundFee+refundFee > order.TotalFee:
+		errCode, errMsg = "INVALID_REQUEST", "退款金额大于支付金额"
+	default:
+		order.RefundFee += refundFee
+		order.TradeState = TradeStateRefund
+	}
+	refundID := "5000" + strconv.FormatInt(s.nextID(), 10)
+	s.mu.Unlock()
+
+	if errCode != "" {
+		s.writeResult(w, errCode, errMsg, nil)
+		return
+	}
+	s.writeResult(w, "", "", map[string]string{
+		"out_trade_no":  req["out_trade_no"],
+		"out_refund_no": req["out_refund_no"],
+		"refund_id":     refundID,
+		"refund_fee":    req["refund_fee"],
+	})
+}
+
+//writeResult 返回业务结果，errCode为空表示成功
+func (s *Server) writeResult(w http.ResponseWriter, errCode, errMsg string, resp map[string]string) {
+	if resp == nil {
+		resp = make(map[string]string)
+	}
+	resp["return_code"] = base.ReturnCodeSuccess
+	resp["return_msg"] = "OK"
+	resp["appid"] = s.AppID
+	resp["mch_id"] = s.MchID
+	resp["nonce_str"] = util.RandomStr(16)
+	if errCode == "" {
+		resp["result_code"] = base.ResultCodeSuccess
+	} else {
+		resp["result_code"] = base.ResultCodeFail
+		resp["err_code"] = errCode
+		resp["err_code_des"] = errMsg
+	}
+	s.writeXML(w, s.signed(resp))
+}
+
+func (s *Server) signed(m map[string]string) map[string]string {
+	delete(m, "sign")
+	m["sign"] = base.Sign(m, s.MchAPIKey, nil)
+	return m
+}
+
+func (s *Server) writeXML(w http.ResponseWriter, m map[string]string) {
+	var buf bytes.Buffer
+	base.FormatMapToXML(&buf, m)
+	w.Header().Set("Content-Type", "text/xml; charset=utf-8")
+	w.Write(buf.Bytes())
+}
@@ -0,0 +1,202 @@
+package wxtest
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+//Media 上传的临时素材
+type Media struct {
+	MediaID     string
+	Type        string
+	Filename    string
+	ContentType string
+	Content     []byte
+}
+
+func (s *Server) registerMp(mux *http.ServeMux) {
+	mux.HandleFunc("/cgi-bin/menu/create", s.withToken(s.handleMenuCreate))
+	mux.HandleFunc("/cgi-bin/menu/get", s.withToken(s.handleMenuGet))
+	mux.HandleFunc("/cgi-bin/menu/delete", s.withToken(s.handleMenuDelete))
+	mux.HandleFunc("/cgi-bin/user/info", s.withToken(s.handleUserInfo))
+	mux.HandleFunc("/cgi-bin/message/template/send", s.withToken(s.handleTemplateSend))
+	mux.HandleFunc("/cgi-bin/message/custom/send", s.withToken(s.handleCustomSend))
+	mux.HandleFunc("/cgi-bin/media/upload", s.withToken(s.handleMediaUpload))
+}
+
+func (s *Server) withToken(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.checkToken(w, r) {
+			h(w, r)
+		}
+	}
+}
+
+//Menu 当前的菜单按钮（button数组），没有菜单时返回nil
+func (s *Server) Menu() json.RawMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.menu
+}
+
+//SetUser 设置 /cgi-bin/user/info 返回的用户信息，info会被编码为json，例如 user.Info
+func (s *Server) SetUser(openID string, info interface{}) error {
+	data, err := json.Marshal(info)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.users[openID] = data
+	s.mu.Unlock()
+	return nil
+}
+
+//SentTemplates 收到的模板消息请求体
+func (s *Server) SentTemplates() []json.RawMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]json.RawMessage(nil), s.templates...)
+}
+
+//CustomMessages 收到的客服消息请求体
+func (s *Server) CustomMessages() []json.RawMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]json.RawMessage(nil), s.customMessages...)
+}
+
+//Media 上传的临时素材
+func (s *Server) Media() []Media {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Media(nil), s.media...)
+}
+
+func (s *Server) handleMenuCreate(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Button json.RawMessage `json:"button"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || len(req.Button) == 0 {
+		writeError(w, 40016, "invalid button size")
+		return
+	}
+	s.mu.Lock()
+	s.menu = req.Button
+	s.mu.Unlock()
+	writeJSON(w, map[string]interface{}{"errcode": 0, "errmsg": "ok"})
+}
+
+func (s *Server) handleMenuGet(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	menu := s.menu
+	s.mu.Unlock()
+	if menu == nil {
+		writeError(w, 46003, "menu no exist")
+		return
+	}
+	writeJSON(w, map[string]interface{}{"menu": map[string]interface{}{"button": menu}})
+}
+
+func (s *Server) handleMenuDelete(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.menu = nil
+	s.mu.Unlock()
+	writeJSON(w, map[string]interface{}{"errcode": 0, "errmsg": "ok"})
+}
+
+func (s *Server) handleUserInfo(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	info, ok := s.users[r.URL.Query().Get("openid")]
+	s.mu.Unlock()
+	if !ok {
+		writeError(w, 40003, "invalid openid")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json; encoding=utf-8")
+	w.Write(info)
+}
+
+func (s *Server) handleTemplateSend(w http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(r.Body)
+	var req struct {
+		ToUser     string `json:"touser"`
+		TemplateID string `json:"template_id"`
+	}
+	if err != nil || json.Unmarshal(body, &req) != nil {
+		writeError(w, 47001, "data format error")
+		return
+	}
+	if req.ToUser == "" {
+		writeError(w, 40003, "invalid openid")
+		return
+	}
+	if req.TemplateID == "" {
+		writeError(w, 40037, "invalid template_id")
+		return
+	}
+	s.mu.Lock()
+	s.templates = append(s.templates, body)
+	msgID := s.nextID()
+	s.mu.Unlock()
+	writeJSON(w, map[string]interface{}{"errcode": 0, "errmsg": "ok", "msgid": msgID})
+}
+
+func (s *Server) handleCustomSend(w http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(r.Body)
+	var req struct {
+		ToUser  string `json:"touser"`
+		MsgType string `json:"msgtype"`
+	}
+	if err != nil || json.Unmarshal(body, &req) != nil {
+		writeError(w, 47001, "data format error")
+		return
+	}
+	if req.ToUser == "" {
+		writeError(w, 40003, "invalid openid")
+		return
+	}
+	if req.MsgType == "" {
+		writeError(w, 40008, "invalid message type")
+		return
+	}
+	s.mu.Lock()
+	s.customMessages = append(s.customMessages, body)
+	s.mu.Unlock()
+	writeJSON(w, map[string]interface{}{"errcode": 0, "errmsg": "ok"})
+}
+
+func (s *Server) handleMediaUpload(w http.ResponseWriter, r *http.Request) {
+	mediaType := r.URL.Query().Get("type")
+	switch mediaType {
+	case "image", "voice", "video", "thumb":
+	default:
+		writeError(w, 40004, "invalid media type")
+		return
+	}
+	file, header, err := r.FormFile("media")
+	if err != nil {
+		writeError(w, 41005, "media data missing")
+		return
+	}
+	defer file.Close()
+	content, err := ioutil.ReadAll(file)
+	if err != nil {
+		writeError(w, 41005, "media data missing")
+		return
+	}
+
+	s.mu.Lock()
+	media := Media{
+		MediaID:     "media-" + strconv.FormatInt(s.nextID(), 10),
+		Type:        mediaType,
+		Filename:    header.Filename,
+		ContentType: header.Header.Get("Content-Type"),
+		Content:     content,
+	}
+	s.media = append(s.media, media)
+	s.mu.Unlock()
+	writeJSON(w, map[string]interface{}{"type": mediaType, "media_id": media.MediaID, "created_at": time.Now().Unix()})
+}
@@ -0,0 +1,99 @@
+package wxtest
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/MrCHI/gowechat/mch/base"
+	"github.com/MrCHI/gowechat/mp/message"
+	"github.com/MrCHI/gowechat/util"
+	"github.com/MrCHI/gowechat/wxcontext"
+)
+
+//TextMessage 用户发送给公众号的文本消息
+func (s *Server) TextMessage(fromUser, content string) string {
+	return fmt.Sprintf("<xml><ToUserName><![CDATA[%s]]></ToUserName><FromUserName><![CDATA[%s]]></FromUserName>"+
+		"<CreateTime>%d</CreateTime><MsgType><![CDATA[text]]></MsgType><Content><![CDATA[%s]]></Content>"+
+		"<MsgId>%d</MsgId></xml>", s.OriginalID, fromUser, time.Now().Unix(), content, time.Now().UnixNano())
+}
+
+//PushMessage 像微信服务器一样把消息推送给handler，encrypt为true时使用安全模式
+func (s *Server) PushMessage(handler http.Handler, msgXML string, encrypt bool) *httptest.ResponseRecorder {
+	mode := wxcontext.EncryptModePlain
+	if encrypt {
+		mode = wxcontext.EncryptModeSafe
+	}
+	return s.PushMessageMode(handler, msgXML, mode)
+}
+
+//PushMessageMode 按指定的加解密方式推送消息，兼容模式下消息体中同时有明文字段和Encrypt
+func (s *Server) PushMessageMode(handler http.Handler, msgXML string, mode wxcontext.EncryptMode) *httptest.ResponseRecorder {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	nonce := util.RandomStr(10)
+	query := url.Values{}
+	query.Set("timestamp", timestamp)
+	query.Set("nonce", nonce)
+	query.Set("signature", util.Signature(s.Token, timestamp, nonce))
+
+	body := []byte(msgXML)
+	if mode == wxcontext.EncryptModeCompatible || mode == wxcontext.EncryptModeSafe {
+		encrypted, err := util.EncryptMsg([]byte(util.RandomStr(16)), body, s.AppID, s.EncodingAESKey)
+		if err != nil {
+			panic("wxtest: " + err.Error())
+		}
+		query.Set("encrypt_type", "aes")
+		query.Set("msg_signature", util.Signature(s.Token, timestamp, nonce, string(encrypted)))
+		if mode == wxcontext.EncryptModeSafe {
+			body, _ = xml.Marshal(message.EncryptedXMLMsg{ToUserName: s.OriginalID, EncryptedMsg: string(encrypted)})
+		} else {
+			i := bytes.LastIndex(body, []byte("</xml>"))
+			if i < 0 {
+				panic("wxtest: message is not <xml>")
+			}
+			body = []byte(fmt.Sprintf("%s<Encrypt><![CDATA[%s]]></Encrypt></xml>", body[:i], encrypted))
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/?"+query.Encode(), bytes.NewReader(body))
+	req.Header.Set("Content-Type", "text/xml")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+//DecryptReply 校验安全模式下的回复签名并解密出明文xml
+func (s *Server) DecryptReply(body []byte) ([]byte, error) {
+	var reply message.ResponseEncryptedXMLMsg
+	if err := xml.Unmarshal(body, &reply); err != nil {
+		return nil, err
+	}
+	signature := util.Signature(s.Token, strconv.FormatInt(reply.Timestamp, 10), reply.Nonce, reply.EncryptedMsg)
+	if signature != reply.MsgSignature {
+		return nil, fmt.Errorf("wxtest: reply signature mismatch")
+	}
+	_, raw, err := util.DecryptMsg(s.AppID, reply.EncryptedMsg, s.EncodingAESKey)
+	return raw, err
+}
+
+//PushPayNotify 模拟用户支付订单，并把带签名的支付结果通知推送给handler
+func (s *Server) PushPayNotify(handler http.Handler, outTradeNo string) (*httptest.ResponseRecorder, error) {
+	notify, ok := s.PayOrder(outTradeNo)
+	if !ok {
+		return nil, fmt.Errorf("wxtest: order %s does not exist or is not NOTPAY", outTradeNo)
+	}
+	var buf bytes.Buffer
+	if err := base.FormatMapToXML(&buf, notify); err != nil {
+		return nil, err
+	}
+	req := httptest.NewRequest(http.MethodPost, "/", &buf)
+	req.Header.Set("Content-Type", "text/xml")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec, nil
+}
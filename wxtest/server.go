@@ -0,0 +1,223 @@
+//Package wxtest 在进程内模拟微信服务器，用于不依赖网络的集成测试
+//
+//	srv := wxtest.NewServer()
+//	defer srv.Close()
+//	wc := gowechat.NewWechat(srv.Config())
+//
+//支持 access_token（普通和稳定版，带过期）、菜单、用户信息、模板消息、临时素材上传，
+//商户平台的统一下单、查询订单、退款（校验并返回签名），
+//以及向业务的 http.Handler 推送（加密）消息和支付结果通知。
+package wxtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	"github.com/MrCHI/gowechat/cache"
+	"github.com/MrCHI/gowechat/util"
+	"github.com/MrCHI/gowechat/wxcontext"
+)
+
+//DefaultTokenTTL access_token的默认有效期
+const DefaultTokenTTL = 7200 * time.Second
+
+//Server 模拟的微信服务器，账号信息在NewServer时随机生成，可以在第一次请求前修改
+type Server struct {
+	*httptest.Server
+
+	AppID          string
+	AppSecret      string
+	Token          string
+	EncodingAESKey string
+	OriginalID     string
+	MchID          string
+	MchAPIKey      string
+	TokenTTL       time.Duration
+
+	certPEM, keyPEM string
+
+	mu             sync.Mutex
+	tokens         map[string]time.Time
+	stableToken    string
+	tokenRequests  int
+	menu           json.RawMessage
+	users          map[string]json.RawMessage
+	templates      []json.RawMessage
+	customMessages []json.RawMessage
+	media          []Media
+	orders         map[string]*Order
+	seq            int64
+}
+
+//NewServer 启动
+func NewServer() *Server {
+	s := &Server{
+		AppID:          "wx" + util.RandomStr(16),
+		AppSecret:      util.RandomStr(32),
+		Token:          util.RandomStr(16),
+		EncodingAESKey: util.RandomStr(43),
+		OriginalID:     "gh_" + util.RandomStr(12),
+		MchID:          "1900000109",
+		MchAPIKey:      util.RandomStr(32),
+		TokenTTL:       DefaultTokenTTL,
+		tokens:         make(map[string]time.Time),
+		users:          make(map[string]json.RawMessage),
+		orders:         make(map[string]*Order),
+	}
+	s.certPEM, s.keyPEM = newCert()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/cgi-bin/token", s.handleToken)
+	mux.HandleFunc("/cgi-bin/stable_token", s.handleStableToken)
+	s.registerMp(mux)
+	s.registerMch(mux)
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		writeJSON(w, map[string]interface{}{"errcode": -1, "errmsg": "wxtest: " + r.URL.Path + " not implemented"})
+	})
+	s.Server = httptest.NewServer(mux)
+	return s
+}
+
+//Config 指向该服务器的配置，使用独立的内存缓存，包含商户平台参数和测试证书
+func (s *Server) Config() wxcontext.Config {
+	return wxcontext.Config{
+		AppID:          s.AppID,
+		AppSecret:      s.AppSecret,
+		Token:          s.Token,
+		EncodingAESKey: s.EncodingAESKey,
+		OriginalID:     s.OriginalID,
+		Cache:          cache.NewMemory(0),
+		Endpoint:       wxcontext.AllEndpoints(s.URL),
+		MchID:          s.MchID,
+		MchAPIKey:      s.MchAPIKey,
+		SslCertContent: s.certPEM,
+		SslKeyContent:  s.keyPEM,
+	}
+}
+
+//TokenRequests /cgi-bin/token 和 /cgi-bin/stable_token 被请求的次数
+func (s *Server) TokenRequests() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.tokenRequests
+}
+
+//ExpireTokens 使已经发放的access_token全部过期，之后的请求返回42001
+func (s *Server) ExpireTokens() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for token := range s.tokens {
+		s.tokens[token] = time.Now().Add(-time.Second)
+	}
+}
+
+//handleToken 和微信一样，发放新token后之前的普通token失效（稳定版token不受影响）
+func (s *Server) handleToken(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if query.Get("grant_type") != "client_credential" {
+		writeError(w, 40002, "invalid grant_type")
+		return
+	}
+	if !s.checkSecret(w, query.Get("appid"), query.Get("secret")) {
+		return
+	}
+
+	s.mu.Lock()
+	s.tokenRequests++
+	for token := range s.tokens {
+		if token != s.stableToken {
+			delete(s.tokens, token)
+		}
+	}
+	token, expiresIn := s.issueToken()
+	s.mu.Unlock()
+	writeJSON(w, map[string]interface{}{"access_token": token, "expires_in": expiresIn})
+}
+
+//handleStableToken 稳定版token在有效期内重复获取返回同一个，force_refresh时重新发放
+func (s *Server) handleStableToken(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		GrantType    string `json:"grant_type"`
+		AppID        string `json:"appid"`
+		Secret       string `json:"secret"`
+		ForceRefresh bool   `json:"force_refresh"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, 47001, "data format error")
+		return
+	}
+	if req.GrantType != "client_credential" {
+		writeError(w, 40002, "invalid grant_type")
+		return
+	}
+	if !s.checkSecret(w, req.AppID, req.Secret) {
+		return
+	}
+
+	s.mu.Lock()
+	s.tokenRequests++
+	expireAt, ok := s.tokens[s.stableToken]
+	if req.ForceRefresh || !ok || time.Now().After(expireAt) {
+		delete(s.tokens, s.stableToken)
+		s.stableToken, _ = s.issueToken()
+		expireAt = s.tokens[s.stableToken]
+	}
+	token := s.stableToken
+	s.mu.Unlock()
+	writeJSON(w, map[string]interface{}{"access_token": token, "expires_in": int64(time.Until(expireAt) / time.Second)})
+}
+
+func (s *Server) checkSecret(w http.ResponseWriter, appID, secret string) bool {
+	switch {
+	case appID != s.AppID:
+		writeError(w, 40013, "invalid appid")
+		return false
+	case secret != s.AppSecret:
+		writeError(w, 40001, "invalid credential, access_token is invalid or not latest")
+		return false
+	}
+	return true
+}
+
+//issueToken 发放新token，调用方需持有mu
+func (s *Server) issueToken() (token string, expiresIn int64) {
+	token = util.RandomStr(64)
+	s.tokens[token] = time.Now().Add(s.TokenTTL)
+	return token, int64(s.TokenTTL / time.Second)
+}
+
+//checkToken 检查access_token参数，无效时写入错误并返回false
+func (s *Server) checkToken(w http.ResponseWriter, r *http.Request) bool {
+	s.mu.Lock()
+	expireAt, ok := s.tokens[r.URL.Query().Get("access_token")]
+	s.mu.Unlock()
+	switch {
+	case !ok:
+		writeError(w, 40001, "invalid credential, access_token is invalid or not latest")
+		return false
+	case time.Now().After(expireAt):
+		writeError(w, 42001, "access_token expired")
+		return false
+	}
+	return true
+}
+
+//nextID 生成递增id，调用方需持有mu
+func (s *Server) nextID() int64 {
+	s.seq++
+	return s.seq
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; encoding=utf-8")
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, code int64, msg string) {
+	writeJSON(w, map[string]interface{}{"errcode": code, "errmsg": fmt.Sprintf("%s rid: %s", msg, util.RandomStr(8))})
+}
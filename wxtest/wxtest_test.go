@@ -0,0 +1,202 @@
+package wxtest_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/MrCHI/gowechat"
+	"github.com/MrCHI/gowechat/mch/base"
+	"github.com/MrCHI/gowechat/mch/pay"
+	"github.com/MrCHI/gowechat/mp/material"
+	"github.com/MrCHI/gowechat/mp/menu"
+	"github.com/MrCHI/gowechat/mp/message"
+	"github.com/MrCHI/gowechat/wxtest"
+)
+
+func TestMenuAndTokenExpiry(t *testing.T) {
+	srv := wxtest.NewServer()
+	defer srv.Close()
+	mp, err := gowechat.NewWechat(srv.Config()).MpMgr()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	btn := new(menu.Button)
+	btn.SetClickButton("今日歌曲", "V1001_TODAY_MUSIC")
+	if err := mp.GetMenu().SetMenu([]*menu.Button{btn}); err != nil {
+		t.Fatal(err)
+	}
+	srv.ExpireTokens()
+	res, err := mp.GetMenu().GetMenu()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Menu.Button) != 1 || res.Menu.Button[0].Key != "V1001_TODAY_MUSIC" {
+		t.Errorf("unexpected menu %+v", res.Menu)
+	}
+	if n := srv.TokenRequests(); n != 2 {
+		t.Errorf("token requested %d times, want 2", n)
+	}
+}
+
+func TestStableTokenSharedBetweenSystems(t *testing.T) {
+	srv := wxtest.NewServer()
+	defer srv.Close()
+	newMp := func(stable bool) *gowechat.MpMgr {
+		cfg := srv.Config() //每次都是独立的缓存，相当于不同的系统
+		cfg.UseStableToken = stable
+		mp, err := gowechat.NewWechat(cfg).MpMgr()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return mp
+	}
+	a, b := newMp(true), newMp(true)
+	tokenA, err := a.GetAccessToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tokenB, _ := b.GetAccessToken(); tokenB != tokenA {
+		t.Errorf("stable token changed between systems: %q != %q", tokenB, tokenA)
+	}
+	//普通模式获取token不影响稳定版
+	if _, err := newMp(false).GetAccessToken(); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.GetMenu().DeleteMenu(); err != nil {
+		t.Fatal(err)
+	}
+	if n := srv.TokenRequests(); n != 3 {
+		t.Errorf("token requested %d times, want 3", n)
+	}
+
+	res, err := b.Context.ForceRefreshAccessToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.AccessToken == tokenA {
+		t.Error("force_refresh should issue a new token")
+	}
+	//a的token已经失效，重新获取后重试
+	if err := a.GetMenu().DeleteMenu(); err != nil {
+		t.Fatal(err)
+	}
+	if n := srv.TokenRequests(); n != 5 {
+		t.Errorf("token requested %d times, want 5", n)
+	}
+}
+
+func TestMediaUploadReader(t *testing.T) {
+	srv := wxtest.NewServer()
+	defer srv.Close()
+	mp, err := gowechat.NewWechat(srv.Config()).MpMgr()
+	if err != nil {
+		t.Fatal(err)
+	}
+	//没有大小的Reader，使用chunked上传
+	content := strings.Repeat("png", 1000)
+	r := ioutil.NopCloser(strings.NewReader(content))
+	media, err := mp.GetMaterial().MediaUploadReader(material.MediaTypeImage, "qrcode.png", "", r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	uploaded := srv.Media()
+	if len(uploaded) != 1 || uploaded[0].MediaID != media.MediaID {
+		t.Fatalf("unexpected media %+v", uploaded)
+	}
+	if got := uploaded[0]; got.Filename != "qrcode.png" || got.ContentType != "image/png" || string(got.Content) != content {
+		t.Errorf("unexpected upload %s %s %d bytes", got.Filename, got.ContentType, len(got.Content))
+	}
+}
+
+func TestPayFlow(t *testing.T) {
+	srv := wxtest.NewServer()
+	defer srv.Close()
+	mch, err := gowechat.NewWechat(srv.Config()).MchMgr()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := mch.GetPay()
+
+	codeURL, err := p.GetNativePayQrcodePicURL(pay.OrderInput{
+		Body:        "test",
+		OutTradeNum: "T001",
+		TotalFee:    100,
+		IP:          "127.0.0.1",
+		NotifyURL:   "https://example.com/notify",
+		ProductID:   "P1",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(codeURL, "weixin://") {
+		t.Errorf("unexpected code_url %q", codeURL)
+	}
+
+	var paid bool
+	var notifyErr error
+	notify := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := ioutil.ReadAll(r.Body)
+		paid, notifyErr = p.CheckPayNotifyData(data)
+	})
+	if _, err := srv.PushPayNotify(notify, "T001"); err != nil {
+		t.Fatal(err)
+	}
+	if notifyErr != nil || !paid {
+		t.Fatalf("pay notify: paid=%v err=%v", paid, notifyErr)
+	}
+
+	resp, err := p.OrderQuery(signed(srv, map[string]string{"out_trade_no": "T001"}))
+	if err != nil || resp["trade_state"] != wxtest.TradeStateSuccess {
+		t.Fatalf("order query: %v %v", resp, err)
+	}
+	resp, err = p.Refund(signed(srv, map[string]string{
+		"out_trade_no":  "T001",
+		"out_refund_no": "R001",
+		"total_fee":     "100",
+		"refund_fee":    "100",
+	}))
+	if err != nil || resp["result_code"] != "SUCCESS" {
+		t.Fatalf("refund: %v %v", resp, err)
+	}
+	if order, _ := srv.Order("T001"); order.TradeState != wxtest.TradeStateRefund {
+		t.Errorf("trade state %s, want REFUND", order.TradeState)
+	}
+}
+
+func TestPushEncryptedMessage(t *testing.T) {
+	srv := wxtest.NewServer()
+	defer srv.Close()
+	mp, err := gowechat.NewWechat(srv.Config()).MpMgr()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h := mp.GetMsgHandler(r, w)
+		h.SetHandleMessageFunc(func(msg message.MixMessage) *message.Reply {
+			return &message.Reply{MsgType: message.MsgTypeText, MsgData: message.NewText("echo: " + msg.Content)}
+		})
+		if err := h.Handle(); err != nil {
+			t.Error(err)
+		}
+	})
+	rec := srv.PushMessage(handler, srv.TextMessage("user1", "hello"), true)
+	raw, err := srv.DecryptReply(rec.Body.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(raw), "echo: hello") {
+		t.Errorf("unexpected reply %s", raw)
+	}
+}
+
+func signed(srv *wxtest.Server, m map[string]string) map[string]string {
+	m["appid"] = srv.AppID
+	m["mch_id"] = srv.MchID
+	m["nonce_str"] = "nonce"
+	m["sign"] = base.Sign(m, srv.MchAPIKey, nil)
+	return m
+}